@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCMD = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Long: `Inspect the configuration loaded from, in increasing precedence, the
+defaults, the --config file, DCD_* environment variables (e.g.
+DCD_SERVER_PORT for server.port) and flags (e.g. --server.port).`,
+}
+
+var configPrintCMD = &cobra.Command{
+	Use:          "print",
+	Short:        "Print the effective configuration with secrets redacted",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg.Redacted()); err != nil {
+			return err
+		}
+		return enc.Close()
+	},
+}
+
+var configValidateCMD = &cobra.Command{
+	Use:          "validate",
+	Short:        "Validate the configuration and report every invalid key",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := loadConfig(cmd); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+		return err
+	},
+}
+
+func init() {
+	config.RegisterFlags(RootCmd.PersistentFlags())
+	configCMD.AddCommand(configPrintCMD, configValidateCMD)
+	RootCmd.AddCommand(configCMD)
+}
+
+// loadConfig loads and validates the configuration of cmd
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := config.Load(cmd.Flags())
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
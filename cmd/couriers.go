@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/services/delivery"
+	"github.com/spf13/cobra"
+)
+
+// envAPIKey is read when --api_key is not set, so keys stay out of the
+// shell history
+const envAPIKey = "DCD_API_KEY"
+
+var couriersCMD = &cobra.Command{
+	Use:   "couriers",
+	Short: "Import and export courier locations of a running server",
+	Long: `Import and export courier locations through the v1 API of a running
+server. The API key is read from --api_key or the DCD_API_KEY environment
+variable, importing needs the couriers:write scope and exporting the
+dispatch:read scope.`,
+}
+
+var couriersExportCMD = &cobra.Command{
+	Use:          "export",
+	Short:        "Export the known courier locations as JSON or CSV",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newAPIClient(cmd)
+		if err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString("output")
+		format, err := fileFormat(cmd, output)
+		if err != nil {
+			return err
+		}
+
+		var locs []delivery.DeliverManLocation
+		if err := client.do(cmd.Context(), http.MethodGet, "/api/v1/couriers", nil, &locs); err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if output != "" && output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		return delivery.WriteLocations(out, format, locs)
+	},
+}
+
+var couriersImportCMD = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Report the courier locations of a JSON or CSV file",
+	Long: `Report the courier locations of a JSON or CSV file, "-" reads stdin.
+CSV files have a courier_id,lat,lng,updated_at header, updated_at is
+RFC 3339 and may be empty. Locations older than the recorded ones are
+ignored by the server.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newAPIClient(cmd)
+		if err != nil {
+			return err
+		}
+		format, err := fileFormat(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		in := cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		locs, err := delivery.ReadLocations(in, format)
+		if err != nil {
+			return err
+		}
+		for i, loc := range locs {
+			if loc.CourierID == "" {
+				return fmt.Errorf("location %d: %w", i+1, delivery.ErrNoCourierID)
+			}
+		}
+
+		for i, loc := range locs {
+			body := map[string]interface{}{"lat": loc.Lat, "lng": loc.Lng}
+			if !loc.UpdatedAt.IsZero() {
+				body["recorded_at"] = loc.UpdatedAt
+			}
+			path := "/api/v1/couriers/" + url.PathEscape(loc.CourierID) + "/location"
+			if err := client.do(cmd.Context(), http.MethodPost, path, body, nil); err != nil {
+				return fmt.Errorf("courier %s: %w, %d of %d locations imported", loc.CourierID, err, i, len(locs))
+			}
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%d locations imported\n", len(locs))
+		return err
+	},
+}
+
+func init() {
+	flags := couriersCMD.PersistentFlags()
+	flags.String("url", "", "base URL of the server, defaults to localhost on server.port")
+	flags.String("api_key", "", "API key of the client, defaults to $"+envAPIKey)
+	flags.String("format", "", "file format: json or csv, defaults to the file extension or json")
+	flags.Duration("timeout", 10*time.Second, "timeout of each request")
+	_ = couriersCMD.RegisterFlagCompletionFunc("format", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{delivery.FormatJSON, delivery.FormatCSV}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	couriersExportCMD.Flags().StringP("output", "o", "", "file to write, stdout if empty")
+	couriersImportCMD.ValidArgsFunction = func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "csv"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+
+	couriersCMD.AddCommand(couriersExportCMD, couriersImportCMD)
+	RootCmd.AddCommand(couriersCMD)
+}
+
+// fileFormat returns the --format flag or the format of the file name
+func fileFormat(cmd *cobra.Command, name string) (string, error) {
+	format, _ := cmd.Flags().GetString("format")
+	if format == "" {
+		format = delivery.FormatOf(name)
+	}
+	switch format {
+	case "":
+		return delivery.FormatJSON, nil
+	case delivery.FormatJSON, delivery.FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: %q", delivery.ErrUnknownFormat, format)
+	}
+}
+
+// apiClient calls the v1 API of a running server
+type apiClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func newAPIClient(cmd *cobra.Command) (*apiClient, error) {
+	flags := cmd.Flags()
+	baseURL, _ := flags.GetString("url")
+	if baseURL == "" {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return nil, err
+		}
+		scheme := "http"
+		if cfg.Server.TLS.Enabled() {
+			scheme = "https"
+		}
+		baseURL = scheme + "://localhost:" + cfg.Server.Port
+	}
+	apiKey, _ := flags.GetString("api_key")
+	if apiKey == "" {
+		apiKey = os.Getenv(envAPIKey)
+	}
+	timeout, _ := flags.GetDuration("timeout")
+	return &apiClient{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// do sends body as JSON and decodes the details of the response into out,
+// error responses are returned as errors
+func (c *apiClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, c.apiKey)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var e errorx.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, path, res.Status)
+		}
+		return fmt.Errorf("%s %s: %s (%s)", method, path, e.Error, e.Code)
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	success := errorx.Success{Details: out}
+	return json.NewDecoder(res.Body).Decode(&success)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/services/delivery"
+	"github.com/spf13/cobra"
+)
+
+var distanceCMD = &cobra.Command{
+	Use:   "distance [FROM] TO",
+	Short: "Calculate the distance and fee of a delivery",
+	Long: `Calculate the distance in kilometers and the fee of a delivery between
+two "lat,lng" coordinates. FROM defaults to delivery.source.`,
+	Example: `  dcd distance 35.6997,51.3380 35.7448,51.3753
+  dcd distance -- -33.9249,18.4241 -33.9628,18.4098`,
+	Args:         cobra.RangeArgs(1, 2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		from := cfg.Delivery.Source
+		if len(args) == 2 {
+			if from, err = parseLocation(args[0]); err != nil {
+				return err
+			}
+			args = args[1:]
+		}
+		to, err := parseLocation(args[0])
+		if err != nil {
+			return err
+		}
+
+		km := delivery.Distance(from.Lat, from.Lng, to.Lat, to.Lng)
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "distance_km: %.3f\n", km)
+		fmt.Fprintf(out, "fee:         %d\n", cfg.Pricing.Fee(km))
+		fmt.Fprintf(out, "currency:    %s\n", cfg.Pricing.Currency)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(distanceCMD)
+}
+
+// parseLocation parses "lat,lng"
+func parseLocation(s string) (config.Location, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return config.Location{}, fmt.Errorf("invalid location %q, must be lat,lng", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return config.Location{}, fmt.Errorf("invalid latitude %q", parts[0])
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return config.Location{}, fmt.Errorf("invalid longitude %q", parts[1])
+	}
+	loc := config.Location{Lat: lat, Lng: lng}
+	if !loc.Valid() {
+		return config.Location{}, fmt.Errorf("%w: %q", config.ErrInvalidLocation, s)
+	}
+	return loc, nil
+}
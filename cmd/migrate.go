@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx/migrate"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/migrations"
+	"github.com/spf13/cobra"
+)
+
+var errNoDatabase = errors.New("no database is configured, set db.driver and db.dsn")
+
+var migrateCMD = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema",
+	Long: `Apply and revert the SQL migrations embedded in the binary on the
+database of db.driver and db.dsn. Applied migrations are recorded with a
+checksum in the schema_migrations table, and concurrent runs wait for each
+other up to --lock_timeout.`,
+}
+
+var migrateUpCMD = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the pending migrations",
+	Example: `  dcd migrate up --dry_run
+  dcd migrate up --to 3`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetInt64("to")
+		return runMigrator(cmd, func(ctx context.Context, m *migrate.Migrator) ([]migrate.Step, error) {
+			if dryRun(cmd) {
+				return m.PlanUp(ctx, to)
+			}
+			return m.Up(ctx, to)
+		})
+	},
+}
+
+var migrateDownCMD = &cobra.Command{
+	Use:          "down",
+	Short:        "Revert the last applied migrations",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		if steps < 1 {
+			return fmt.Errorf("invalid --steps %d, must be positive", steps)
+		}
+		return runMigrator(cmd, func(ctx context.Context, m *migrate.Migrator) ([]migrate.Step, error) {
+			if dryRun(cmd) {
+				return m.PlanDown(ctx, steps)
+			}
+			return m.Down(ctx, steps)
+		})
+	},
+}
+
+var migrateStatusCMD = &cobra.Command{
+	Use:          "status",
+	Short:        "List the migrations and whether they are applied",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeDB, err := openMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		statuses, err := m.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			switch {
+			case s.Applied && s.Migration.Up == "":
+				state = "unknown"
+			case s.Modified:
+				state = "modified"
+			case s.Applied:
+				state = "applied"
+			}
+			if s.Applied {
+				appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Migration.Version, s.Migration.Name, state, appliedAt)
+		}
+		return w.Flush()
+	},
+}
+
+var migrateUnlockCMD = &cobra.Command{
+	Use:   "unlock",
+	Short: "Release the lock of a migration run which did not exit cleanly",
+	Long: `Release the lock of a migration run which did not exit cleanly, make
+sure no migration is running first. Postgres releases the locks of closed
+sessions by itself.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeDB, err := openMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		if err := m.Unlock(cmd.Context()); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "migrations are unlocked")
+		return err
+	},
+}
+
+func init() {
+	migrateCMD.PersistentFlags().Duration("lock_timeout", time.Minute, "how long to wait for a concurrent migration run")
+	for _, c := range []*cobra.Command{migrateUpCMD, migrateDownCMD} {
+		c.Flags().Bool("dry_run", false, "print the SQL of the steps without running them")
+	}
+	migrateUpCMD.Flags().Int64("to", 0, "apply the migrations up to this version, all of them if 0")
+	migrateDownCMD.Flags().Int("steps", 1, "number of migrations to revert")
+
+	migrateCMD.AddCommand(migrateUpCMD, migrateDownCMD, migrateStatusCMD, migrateUnlockCMD)
+	RootCmd.AddCommand(migrateCMD)
+}
+
+func dryRun(cmd *cobra.Command) bool {
+	v, _ := cmd.Flags().GetBool("dry_run")
+	return v
+}
+
+// openMigrator opens the configured database, closeDB must be called when
+// err is nil
+func openMigrator(cmd *cobra.Command) (m *migrate.Migrator, closeDB func(), err error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.DB.DSN == "" {
+		return nil, nil, errNoDatabase
+	}
+	dialect, err := migrate.DialectOf(cfg.DB.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+	migs, err := migrate.Load(migrations.FS, dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := dbx.Open(cmd.Context(), cfg.DB.Driver, cfg.DB.DSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	timeout, _ := cmd.Flags().GetDuration("lock_timeout")
+	if m, err = migrate.New(db, migs, migrate.WithLockTimeout(timeout)); err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+	return m, func() { _ = db.Close() }, nil
+}
+
+// runMigrator runs fn and prints the steps it returns, with their SQL on
+// dry runs
+func runMigrator(cmd *cobra.Command, fn func(ctx context.Context, m *migrate.Migrator) ([]migrate.Step, error)) error {
+	m, closeDB, err := openMigrator(cmd)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	steps, err := fn(cmd.Context(), m)
+	printSteps(cmd.OutOrStdout(), steps, dryRun(cmd))
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "no migration to run")
+	}
+	return err
+}
+
+func printSteps(w io.Writer, steps []migrate.Step, withSQL bool) {
+	for _, s := range steps {
+		if !withSQL {
+			fmt.Fprintf(w, "%-4s %s\n", s.Direction, s.Migration)
+			continue
+		}
+		fmt.Fprintf(w, "-- %s %s\n%s\n", s.Direction, s.Migration, s.SQL())
+	}
+}
@@ -1,26 +1,27 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/lifecycle"
 	"github.com/spf13/cobra"
 )
-// RootCmd is the root command!
+
+// RootCmd is the root command of the dcd CLI
 var RootCmd = &cobra.Command{
-	Use:   "hugo",
-	Short: "Hugo is a very fast static site generator",
-	Long: `A Fast and Flexible Static Site Generator built with
-				  love by spf13 and friends in Go.
-				  Complete documentation is available at http://hugo.spf13.com`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Hi, welcome to boilerplate")
-	},
+	Use:   "dcd",
+	Short: "Calculate the distance of couriers to a delivery destination",
+	Long: `dcd serves the delivery API and calculates the distance of couriers to
+the source of a delivery.
+
+Every command reads the configuration from, in increasing precedence,
+the defaults, the --config file, DCD_* environment variables (e.g.
+DCD_SERVER_PORT for server.port) and flags (e.g. --server.port).`,
 }
 
 // Execute runs the main command of the project
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(lifecycle.ExitCode(err))
 	}
 }
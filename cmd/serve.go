@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/server"
+	"github.com/spf13/cobra"
+)
+
+var serveCMD = &cobra.Command{
+	Use:     "serve",
+	Aliases: []string{"run"},
+	Short:   "Serve the HTTP API",
+	Long: `Serve the HTTP API until SIGTERM or SIGINT is received, SIGHUP reloads
+certificates and keys.`,
+	Args: cobra.NoArgs,
+	// errors returned after startup are shutdown failures, not usage errors
+	SilenceUsage: true,
+	RunE:         serveCmdE,
+}
+
+func init() {
+	RootCmd.AddCommand(serveCMD)
+}
+
+func newLogger(cfg *config.Config) (*loggerx.Logger, error) {
+	opts := []loggerx.Option{
+		loggerx.WithLevel(cfg.Logging.Level),
+		loggerx.WithEncoding(cfg.Logging.Encoding),
+	}
+	if cfg.Logging.File.Path != "" {
+		opts = append(opts, loggerx.WithFile(loggerx.FileOptions{
+			Path:       cfg.Logging.File.Path,
+			MaxSizeMB:  cfg.Logging.File.MaxSizeMB,
+			MaxBackups: cfg.Logging.File.MaxBackups,
+			MaxAgeDays: cfg.Logging.File.MaxAgeDays,
+			Compress:   cfg.Logging.File.Compress,
+		}))
+	}
+	if cfg.Logging.Sentry.DSN != "" {
+		opts = append(opts, loggerx.WithSentry(cfg.Logging.Sentry.DSN, cfg.Logging.Sentry.Tags))
+	}
+	return loggerx.New(cfg.Logging.Mode, cfg.ServiceName, opts...)
+}
+
+func serveCmdE(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	logger, err := newLogger(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	return server.RunServer(cfg, logger)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Build information, set with
+// -ldflags "-X github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/cmd.version=v1.2.3 ..."
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+var versionCMD = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version and build information",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		if short, _ := cmd.Flags().GetBool("short"); short {
+			fmt.Fprintln(out, buildVersion())
+			return
+		}
+		fmt.Fprintf(out, "version:    %s\n", buildVersion())
+		fmt.Fprintf(out, "commit:     %s\n", orUnknown(commit))
+		fmt.Fprintf(out, "built:      %s\n", orUnknown(buildDate))
+		fmt.Fprintf(out, "go version: %s\n", runtime.Version())
+		fmt.Fprintf(out, "platform:   %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	},
+}
+
+func init() {
+	versionCMD.Flags().Bool("short", false, "print the version only")
+	RootCmd.AddCommand(versionCMD)
+}
+
+// buildVersion returns the version set at link time, or the module version
+// when installed with go install
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, file string, args ...string) *Config {
+	t.Helper()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(flags)
+	if file != "" {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o600))
+		args = append(args, "--config", path)
+	}
+	require.NoError(t, flags.Parse(args))
+	cfg, err := Load(flags)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg := load(t, "")
+	assert.Equal(t, "5050", cfg.Server.Port)
+	assert.Empty(t, cfg.Server.TrustedProxies, "proxies must be configured explicitly")
+	assert.Equal(t, 5*time.Minute, cfg.Auth.Device.RotationGrace)
+	assert.Equal(t, cfg.ServiceName, cfg.Tracing.ServiceName)
+	assert.True(t, cfg.Metrics.Public, "/metrics is served without authentication")
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := `
+server:
+  port: "6000"
+  read_timeout: 3s
+logging:
+  level: warn
+  mode: stage
+delivery:
+  couriers:
+    - {lat: 35.7, lng: 51.4}
+ratelimit:
+  routes:
+    GET /api/v1/list: 2/s
+`
+	t.Setenv("DCD_SERVER_PORT", "7000")
+	t.Setenv("DCD_LOGGING_LEVEL", "error")
+	t.Setenv("DCD_AUTH_API_KEYS", "backoffice=k1,dispatcher=k2")
+	cfg := load(t, file, "--logging.level", "debug")
+
+	assert.Equal(t, "7000", cfg.Server.Port, "env overrides file")
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout, "file overrides defaults")
+	assert.Equal(t, "debug", cfg.Logging.Level, "flag overrides env")
+	assert.Equal(t, "stage", cfg.Logging.Mode)
+	assert.Equal(t, []Location{{Lat: 35.7, Lng: 51.4}}, cfg.Delivery.Couriers)
+	assert.Equal(t, map[string]string{"backoffice": "k1", "dispatcher": "k2"}, cfg.Auth.APIKeys)
+
+	policy, err := cfg.RateLimitPolicy()
+	require.NoError(t, err)
+	assert.Contains(t, policy.Routes, "GET /api/v1/list", "methods of lower cased keys are restored")
+}
+
+func TestLoadJSONEnv(t *testing.T) {
+	t.Setenv("DCD_DELIVERY_COURIERS", `[{"lat":1,"lng":2}]`)
+	cfg := load(t, "")
+	assert.Equal(t, []Location{{Lat: 1, Lng: 2}}, cfg.Delivery.Couriers)
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(flags)
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  prot: 1\n"), 0o600))
+	require.NoError(t, flags.Parse([]string{"--config", path}))
+
+	_, err := Load(flags)
+	assert.ErrorContains(t, err, "prot")
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = "70000"
+	cfg.Logging.Mode = "prod"
+	cfg.Server.CORS.AllowOrigins = []string{"*"}
+	cfg.Server.CORS.AllowCredentials = true
+	cfg.Delivery.Couriers = []Location{{Lat: 1, Lng: 1}, {Lat: 91, Lng: 1}}
+	cfg.Pricing.Currency = "euro"
+	cfg.DB.Replicas = []string{""}
+
+	err := cfg.Validate()
+	var errs Errors
+	require.True(t, errors.As(err, &errs))
+
+	keys := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		keys = append(keys, fe.Key)
+	}
+	assert.Equal(t, []string{
+		"server.port",
+		"server.cors.allow_credentials",
+		"logging.mode",
+		"db.dsn",
+		"db.replicas[0]",
+		"delivery.couriers[1]",
+		"pricing.currency",
+	}, keys)
+	assert.ErrorIs(t, err, ErrUnknownMode)
+	assert.False(t, errors.Is(err, ErrNoAdminAuth))
+	var fe *FieldError
+	require.True(t, errors.As(err, &fe))
+	assert.Equal(t, "server.port", fe.Key)
+	assert.Contains(t, err.Error(), "server.port: must be a port between 1 and 65535: \"70000\"")
+
+	cfg = Default()
+	cfg.DB.DSN = "postgres://primary/dcd"
+	cfg.DB.Replicas = []string{"postgres://replica/dcd"}
+	cfg.DB.ReplicaCheckInterval = 0
+	err = cfg.Validate()
+	assert.ErrorIs(t, err, ErrNotPositive)
+	assert.Contains(t, err.Error(), "db.replica_check_interval")
+
+	cfg = Default()
+	cfg.Metrics.Public = false
+	err = cfg.Validate()
+	assert.ErrorIs(t, err, ErrNoAdminAuth)
+	assert.Contains(t, err.Error(), "metrics.public")
+	cfg.Auth.AdminToken = "admin-secret"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.AdminToken = "admin-secret"
+	cfg.Auth.JWT.Secret = "jwt-secret"
+	cfg.Auth.APIKeys = map[string]string{"backoffice": "key-secret"}
+	cfg.Logging.Sentry.DSN = "https://public@o1.ingest.sentry.io/1"
+
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{dsn: "postgres://dcd:pw@db:5432/dcd?sslmode=disable", want: "postgres://dcd:REDACTED@db:5432/dcd?sslmode=disable"},
+		{dsn: "host=db user=dcd password=pw dbname=dcd", want: "host=db user=dcd password=REDACTED dbname=dcd"},
+		{dsn: "dcd:pw@tcp(db:3306)/dcd", want: "dcd:REDACTED@tcp(db:3306)/dcd"},
+		{dsn: "file:dcd.db", want: "file:dcd.db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			cfg.DB.DSN = tt.dsn
+			cfg.DB.Replicas = []string{tt.dsn}
+			r := cfg.Redacted()
+			assert.Equal(t, tt.want, r.DB.DSN)
+			assert.Equal(t, []string{tt.want}, r.DB.Replicas)
+			assert.Equal(t, tt.dsn, cfg.DB.Replicas[0], "the config is not modified")
+			assert.Equal(t, Redacted, r.Auth.AdminToken)
+			assert.Equal(t, Redacted, r.Auth.JWT.Secret)
+			assert.Equal(t, Redacted, r.Auth.APIKeys["backoffice"])
+			assert.Equal(t, "https://REDACTED@o1.ingest.sentry.io/1", r.Logging.Sentry.DSN)
+			assert.Equal(t, "key-secret", cfg.Auth.APIKeys["backoffice"], "the config is not modified")
+		})
+	}
+}
+
+func TestFee(t *testing.T) {
+	p := Pricing{BaseFee: 200, PerKmFee: 50, MinimumFee: 300}
+	assert.Equal(t, int64(300), p.Fee(1))
+	assert.Equal(t, int64(700), p.Fee(10))
+	assert.Equal(t, int64(226), Pricing{BaseFee: 200, PerKmFee: 50}.Fee(0.51))
+}
@@ -1,6 +1,212 @@
 package config
 
+import (
+	"time"
+)
+
+// Config is the configuration of the service. It is loaded by Load from
+// the defaults, a config file, DCD_* environment variables and flags, in
+// increasing precedence.
 type Config struct {
-	Port          string `yaml:"port"`
-	DeliverManLoc string `yaml:"deliver_man_loc"`
+	ServiceName string `mapstructure:"service_name" yaml:"service_name"`
+
+	Server    Server    `mapstructure:"server" yaml:"server"`
+	Auth      Auth      `mapstructure:"auth" yaml:"auth"`
+	RateLimit RateLimit `mapstructure:"ratelimit" yaml:"ratelimit"`
+	Logging   Logging   `mapstructure:"logging" yaml:"logging"`
+	Tracing   Tracing   `mapstructure:"tracing" yaml:"tracing"`
+	Metrics   Metrics   `mapstructure:"metrics" yaml:"metrics"`
+	DB        DB        `mapstructure:"db" yaml:"db"`
+	Delivery  Delivery  `mapstructure:"delivery" yaml:"delivery"`
+	Pricing   Pricing   `mapstructure:"pricing" yaml:"pricing"`
+}
+
+type Server struct {
+	Port         string        `mapstructure:"port" yaml:"port"`
+	ReadTimeout  time.Duration `mapstructure:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout" yaml:"write_timeout"`
+
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// ShutdownDrainDelay is the time between failing readiness and closing
+	// the listeners
+	ShutdownDrainDelay time.Duration `mapstructure:"shutdown_drain_delay" yaml:"shutdown_drain_delay"`
+
+	// TrustedProxies are the CIDRs of proxies whose forwarding headers are
+	// trusted to find the client address, empty to trust none
+	TrustedProxies []string `mapstructure:"trusted_proxies" yaml:"trusted_proxies"`
+
+	CORS     CORS     `mapstructure:"cors" yaml:"cors"`
+	Security Security `mapstructure:"security" yaml:"security"`
+	TLS      TLS      `mapstructure:"tls" yaml:"tls"`
+}
+
+type CORS struct {
+	// AllowOrigins enables CORS for browser clients of these origins, "*"
+	// for any origin without credentials
+	AllowOrigins     []string `mapstructure:"allow_origins" yaml:"allow_origins"`
+	AllowMethods     []string `mapstructure:"allow_methods" yaml:"allow_methods"`
+	AllowHeaders     []string `mapstructure:"allow_headers" yaml:"allow_headers"`
+	ExposeHeaders    []string `mapstructure:"expose_headers" yaml:"expose_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials" yaml:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age" yaml:"max_age"`
+}
+
+type Security struct {
+	// HSTSMaxAge is sent in seconds over TLS, zero disables HSTS
+	HSTSMaxAge int    `mapstructure:"hsts_max_age" yaml:"hsts_max_age"`
+	CSP        string `mapstructure:"csp" yaml:"csp"`
+}
+
+type TLS struct {
+	// CertFile and KeyFile enable HTTPS, the files are reloaded on SIGHUP
+	// and when modified. ClientAuth is none, optional or require, client
+	// certificates are verified with ClientCAFile.
+	CertFile       string        `mapstructure:"cert_file" yaml:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file" yaml:"key_file"`
+	ClientCAFile   string        `mapstructure:"client_ca_file" yaml:"client_ca_file"`
+	ClientAuth     string        `mapstructure:"client_auth" yaml:"client_auth"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval" yaml:"reload_interval"`
+}
+
+type Auth struct {
+	// AdminToken protects the admin endpoints, and /metrics unless it is
+	// public. The admin endpoints are disabled if it is empty and no other
+	// authentication is configured.
+	AdminToken string `mapstructure:"admin_token" yaml:"admin_token"`
+
+	// APIKeys maps client names to their API key and APIKeyScopes maps them
+	// to space separated scopes, e.g. "dispatch:read admin"
+	APIKeys      map[string]string `mapstructure:"api_keys" yaml:"api_keys"`
+	APIKeyScopes map[string]string `mapstructure:"api_key_scopes" yaml:"api_key_scopes"`
+
+	JWT    JWT    `mapstructure:"jwt" yaml:"jwt"`
+	Device Device `mapstructure:"device" yaml:"device"`
+
+	// MTLSScopes maps common names of client certificates to space
+	// separated scopes
+	MTLSScopes map[string]string `mapstructure:"mtls_scopes" yaml:"mtls_scopes"`
+}
+
+type JWT struct {
+	// Secret verifies HS256 tokens and JWKSFile holds the keys of RS256
+	// tokens, the v1 API is open if no authentication is set
+	Secret   string `mapstructure:"secret" yaml:"secret"`
+	JWKSFile string `mapstructure:"jwks_file" yaml:"jwks_file"`
+	Issuer   string `mapstructure:"issuer" yaml:"issuer"`
+	Audience string `mapstructure:"audience" yaml:"audience"`
+}
+
+type Device struct {
+	// TokenTTL expires courier device tokens, zero keeps them until rotated
+	// or revoked. RotationGrace keeps the previous token of a device valid
+	// after a new one is issued.
+	TokenTTL      time.Duration `mapstructure:"token_ttl" yaml:"token_ttl"`
+	RotationGrace time.Duration `mapstructure:"rotation_grace" yaml:"rotation_grace"`
+}
+
+type RateLimit struct {
+	// Default is the limit of a client on the v1 API, e.g. "10/s:20", and
+	// Routes overrides it per route, e.g. "GET /api/v1/list". Empty limits
+	// and a zero quota disable rate limiting.
+	Default    string            `mapstructure:"default" yaml:"default"`
+	Routes     map[string]string `mapstructure:"routes" yaml:"routes"`
+	DailyQuota int64             `mapstructure:"daily_quota" yaml:"daily_quota"`
+}
+
+type Logging struct {
+	// Mode is one of configx.ModeLocal, ModeDev, ModeStage or ModeProd
+	Mode     string `mapstructure:"mode" yaml:"mode"`
+	Level    string `mapstructure:"level" yaml:"level"`
+	Encoding string `mapstructure:"encoding" yaml:"encoding"`
+
+	File    LogFile    `mapstructure:"file" yaml:"file"`
+	Request LogRequest `mapstructure:"request" yaml:"request"`
+	Sentry  Sentry     `mapstructure:"sentry" yaml:"sentry"`
+}
+
+type LogFile struct {
+	// Path enables writing logs to a size rotated file
+	Path       string `mapstructure:"path" yaml:"path"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb" yaml:"max_size_mb"`
+	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups"`
+	MaxAgeDays int    `mapstructure:"max_age_days" yaml:"max_age_days"`
+	Compress   bool   `mapstructure:"compress" yaml:"compress"`
+}
+
+type LogRequest struct {
+	// SampleRate is the fraction of successful requests logged
+	SampleRate float64  `mapstructure:"sample_rate" yaml:"sample_rate"`
+	SkipPaths  []string `mapstructure:"skip_paths" yaml:"skip_paths"`
+}
+
+type Sentry struct {
+	// DSN enables error reporting to Sentry in production mode
+	DSN  string            `mapstructure:"dsn" yaml:"dsn"`
+	Tags map[string]string `mapstructure:"tags" yaml:"tags"`
+}
+
+type Tracing struct {
+	// ServiceName defaults to the service name
+	ServiceName  string  `mapstructure:"service_name" yaml:"service_name"`
+	Exporter     string  `mapstructure:"exporter" yaml:"exporter"`
+	Endpoint     string  `mapstructure:"endpoint" yaml:"endpoint"`
+	SamplerType  string  `mapstructure:"sampler_type" yaml:"sampler_type"`
+	SamplerParam float64 `mapstructure:"sampler_param" yaml:"sampler_param"`
+}
+
+type Metrics struct {
+	// Public serves /metrics without authentication, otherwise only admin
+	// clients are served and admin authentication is required
+	Public bool `mapstructure:"public" yaml:"public"`
+}
+
+type DB struct {
+	Driver string `mapstructure:"driver" yaml:"driver"`
+	// DSN is the connection string, the database is not used if empty
+	DSN string `mapstructure:"dsn" yaml:"dsn"`
+	// Replicas are connection strings of read replicas of the database
+	Replicas []string `mapstructure:"replicas" yaml:"replicas"`
+	// ReplicaMaxLag is the replication lag after which a replica is not
+	// read, zero ignores the lag
+	ReplicaMaxLag time.Duration `mapstructure:"replica_max_lag" yaml:"replica_max_lag"`
+	// ReplicaCheckInterval is how often the replicas are checked, it is
+	// required when there are replicas
+	ReplicaCheckInterval time.Duration `mapstructure:"replica_check_interval" yaml:"replica_check_interval"`
+	// SlowQuery is the duration after which queries are logged, zero
+	// disables the log
+	SlowQuery time.Duration `mapstructure:"slow_query" yaml:"slow_query"`
+}
+
+type Delivery struct {
+	// Source is the location orders are picked up at
+	Source Location `mapstructure:"source" yaml:"source"`
+	// Couriers are static courier locations, reported ones are added at
+	// runtime
+	Couriers []Location `mapstructure:"couriers" yaml:"couriers"`
+	// CourierStaleAfter is the age after which a reported courier location
+	// is considered stale, zero disables the check
+	CourierStaleAfter time.Duration `mapstructure:"courier_stale_after" yaml:"courier_stale_after"`
+}
+
+type Location struct {
+	Lat float64 `mapstructure:"lat" yaml:"lat" json:"lat"`
+	Lng float64 `mapstructure:"lng" yaml:"lng" json:"lng"`
+}
+
+type Pricing struct {
+	// Currency is an ISO 4217 code, fees are in its minor unit
+	Currency   string `mapstructure:"currency" yaml:"currency"`
+	BaseFee    int64  `mapstructure:"base_fee" yaml:"base_fee"`
+	PerKmFee   int64  `mapstructure:"per_km_fee" yaml:"per_km_fee"`
+	MinimumFee int64  `mapstructure:"minimum_fee" yaml:"minimum_fee"`
+}
+
+// Fee returns the delivery fee of a distance in kilometers, in the minor
+// unit of the currency
+func (p Pricing) Fee(km float64) int64 {
+	fee := p.BaseFee + int64(km*float64(p.PerKmFee)+0.5)
+	if fee < p.MinimumFee {
+		return p.MinimumFee
+	}
+	return fee
 }
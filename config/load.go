@@ -0,0 +1,267 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/configx"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix prefixes the environment variables of config keys, dots are
+// replaced by underscores, e.g. DCD_SERVER_PORT for server.port
+const EnvPrefix = "dcd"
+
+// FlagConfig is the flag of the config file, it can also be set with the
+// DCD_CONFIG environment variable
+const FlagConfig = "config"
+
+// flagAnnotation marks the flags registered by RegisterFlags
+const flagAnnotation = "dcd_config_key"
+
+// Default returns the configuration of the keys which are not set
+func Default() *Config {
+	return &Config{
+		ServiceName: "calculate-deliver-to-destination",
+		Server: Server{
+			Port:            "5050",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			TrustedProxies:  []string{},
+			CORS: CORS{
+				AllowMethods:  []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+				AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Accept-Language", "Authorization", "X-Api-Key", "X-Device-Id", "X-Request-Id"},
+				ExposeHeaders: []string{"X-Request-Id", "Content-Language", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "X-Quota-Limit", "X-Quota-Remaining"},
+				MaxAge:        600,
+			},
+			Security: Security{
+				HSTSMaxAge: 31536000,
+				CSP:        "default-src 'none'; frame-ancestors 'none'",
+			},
+			TLS: TLS{
+				ClientAuth:     "none",
+				ReloadInterval: time.Minute,
+			},
+		},
+		Auth: Auth{
+			Device: Device{RotationGrace: 5 * time.Minute},
+		},
+		Logging: Logging{
+			Mode: configx.ModeProd,
+			File: LogFile{MaxSizeMB: 100, MaxBackups: 5},
+			Request: LogRequest{
+				SampleRate: 1,
+				SkipPaths:  []string{"/livez", "/healthz", "/readyz", "/metrics"},
+			},
+		},
+		Tracing: Tracing{
+			Exporter:     "noop",
+			SamplerType:  "const",
+			SamplerParam: 1,
+		},
+		Metrics: Metrics{Public: true},
+		DB: DB{
+			Driver:               "postgres",
+			ReplicaMaxLag:        10 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+			SlowQuery:            200 * time.Millisecond,
+		},
+		Delivery: Delivery{
+			Source: Location{Lat: 55.545454, Lng: 12.5465465},
+		},
+		Pricing: Pricing{Currency: "EUR"},
+	}
+}
+
+// RegisterFlags registers a flag named after every config key on flags,
+// e.g. --server.port, with the default of Default
+func RegisterFlags(flags *pflag.FlagSet) {
+	d := Default()
+	fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+
+	fs.String("service_name", d.ServiceName, "service name reported to logs, sentry and tracing")
+
+	fs.String("server.port", d.Server.Port, "HTTP server listen port")
+	fs.Duration("server.read_timeout", d.Server.ReadTimeout, "deadline for reading a request")
+	fs.Duration("server.write_timeout", d.Server.WriteTimeout, "deadline for writing a response")
+	fs.Duration("server.shutdown_timeout", d.Server.ShutdownTimeout, "deadline for draining requests and workers on shutdown")
+	fs.Duration("server.shutdown_drain_delay", d.Server.ShutdownDrainDelay, "delay between failing readiness and closing listeners")
+	fs.StringSlice("server.trusted_proxies", d.Server.TrustedProxies, "CIDRs of proxies whose forwarding headers are trusted, empty to trust none")
+	fs.StringSlice("server.cors.allow_origins", d.Server.CORS.AllowOrigins, "origins allowed to call the API from browsers, \"*\" for any, CORS is disabled if empty")
+	fs.StringSlice("server.cors.allow_methods", d.Server.CORS.AllowMethods, "methods allowed in CORS requests")
+	fs.StringSlice("server.cors.allow_headers", d.Server.CORS.AllowHeaders, "headers allowed in CORS requests")
+	fs.StringSlice("server.cors.expose_headers", d.Server.CORS.ExposeHeaders, "response headers readable by browsers")
+	fs.Bool("server.cors.allow_credentials", d.Server.CORS.AllowCredentials, "allow cookies and credentials in CORS requests, not allowed with origin \"*\"")
+	fs.Int("server.cors.max_age", d.Server.CORS.MaxAge, "seconds browsers may cache preflight responses")
+	fs.Int("server.security.hsts_max_age", d.Server.Security.HSTSMaxAge, "max-age of the Strict-Transport-Security header sent over TLS, zero disables it")
+	fs.String("server.security.csp", d.Server.Security.CSP, "Content-Security-Policy header, empty disables it")
+	fs.String("server.tls.cert_file", d.Server.TLS.CertFile, "PEM certificate served over HTTPS, HTTP is served if empty")
+	fs.String("server.tls.key_file", d.Server.TLS.KeyFile, "PEM private key of the certificate")
+	fs.String("server.tls.client_ca_file", d.Server.TLS.ClientCAFile, "PEM CA certificates verifying client certificates")
+	fs.String("server.tls.client_auth", d.Server.TLS.ClientAuth, "client certificates: none, optional or require")
+	fs.Duration("server.tls.reload_interval", d.Server.TLS.ReloadInterval, "interval at which modified certificate files are reloaded, zero reloads on SIGHUP only")
+
+	fs.String("auth.admin_token", d.Auth.AdminToken, "bearer token of the admin endpoints and of /metrics unless it is public")
+	fs.StringToString("auth.api_keys", d.Auth.APIKeys, "API keys of clients, e.g. backoffice=key1,dispatcher=key2")
+	fs.StringToString("auth.api_key_scopes", d.Auth.APIKeyScopes, "space separated scopes of API key clients, e.g. backoffice=admin")
+	fs.String("auth.jwt.secret", d.Auth.JWT.Secret, "secret verifying HS256 tokens")
+	fs.String("auth.jwt.jwks_file", d.Auth.JWT.JWKSFile, "JWKS file holding the keys verifying RS256 tokens, reloaded on SIGHUP")
+	fs.String("auth.jwt.issuer", d.Auth.JWT.Issuer, "expected iss claim of tokens")
+	fs.String("auth.jwt.audience", d.Auth.JWT.Audience, "expected aud claim of tokens")
+	fs.Duration("auth.device.token_ttl", d.Auth.Device.TokenTTL, "lifetime of courier device tokens, zero keeps them until rotated or revoked")
+	fs.Duration("auth.device.rotation_grace", d.Auth.Device.RotationGrace, "time the previous token of a device stays valid after rotation")
+	fs.StringToString("auth.mtls_scopes", d.Auth.MTLSScopes, "space separated scopes of client certificate common names, e.g. billing=dispatch:read")
+
+	fs.String("ratelimit.default", d.RateLimit.Default, "rate limit of a client on the v1 API as rate/unit[:burst], e.g. 10/s:20")
+	fs.StringToString("ratelimit.routes", d.RateLimit.Routes, "rate limits of routes, e.g. \"GET /api/v1/list=2/s:5\"")
+	fs.Int64("ratelimit.daily_quota", d.RateLimit.DailyQuota, "requests a client may make per UTC day, zero for no quota")
+
+	fs.String("logging.mode", d.Logging.Mode, "mode: local, development, stage or production")
+	fs.String("logging.level", d.Logging.Level, "minimal log level, defaults to debug in local and development mode and info otherwise")
+	fs.String("logging.encoding", d.Logging.Encoding, "log encoding: json or console, defaults by mode")
+	fs.String("logging.file.path", d.Logging.File.Path, "also write logs to this file")
+	fs.Int("logging.file.max_size_mb", d.Logging.File.MaxSizeMB, "size of the log file before it is rotated")
+	fs.Int("logging.file.max_backups", d.Logging.File.MaxBackups, "number of rotated log files to keep, 0 keeps all")
+	fs.Int("logging.file.max_age_days", d.Logging.File.MaxAgeDays, "days to keep rotated log files, 0 keeps them forever")
+	fs.Bool("logging.file.compress", d.Logging.File.Compress, "gzip rotated log files")
+	fs.Float64("logging.request.sample_rate", d.Logging.Request.SampleRate, "fraction of successful requests which are logged")
+	fs.StringSlice("logging.request.skip_paths", d.Logging.Request.SkipPaths, "request paths which are never logged")
+	fs.String("logging.sentry.dsn", d.Logging.Sentry.DSN, "sentry DSN, errors are reported in production mode only")
+	fs.StringToString("logging.sentry.tags", d.Logging.Sentry.Tags, "tags added to sentry events, e.g. region=eu,cluster=a")
+
+	fs.String("tracing.service_name", d.Tracing.ServiceName, "service name reported to the tracing backend, defaults to service_name")
+	fs.String("tracing.exporter", d.Tracing.Exporter, "tracing exporter: noop, memory, otlp, stdout or file")
+	fs.String("tracing.endpoint", d.Tracing.Endpoint, "otlp collector host:port or URL, or trace file path")
+	fs.String("tracing.sampler_type", d.Tracing.SamplerType, "sampler type: const or probabilistic")
+	fs.Float64("tracing.sampler_param", d.Tracing.SamplerParam, "sampler parameter")
+
+	fs.Bool("metrics.public", d.Metrics.Public, "serve /metrics without authentication, otherwise to admin clients only")
+
+	fs.String("db.driver", d.DB.Driver, "database driver name")
+	fs.String("db.dsn", d.DB.DSN, "database connection string, database is not used if empty")
+	fs.StringSlice("db.replicas", d.DB.Replicas, "connection strings of read replicas")
+	fs.Duration("db.replica_max_lag", d.DB.ReplicaMaxLag, "replication lag after which a replica is not read, 0 ignores the lag")
+	fs.Duration("db.replica_check_interval", d.DB.ReplicaCheckInterval, "how often the replicas are checked, required with replicas")
+	fs.Duration("db.slow_query", d.DB.SlowQuery, "duration after which queries are logged, 0 disables the log")
+
+	fs.Float64("delivery.source.lat", d.Delivery.Source.Lat, "latitude orders are picked up at")
+	fs.Float64("delivery.source.lng", d.Delivery.Source.Lng, "longitude orders are picked up at")
+	fs.String("delivery.couriers", "", "static courier locations as a JSON list, e.g. [{\"lat\":35.7,\"lng\":51.4}]")
+	fs.Duration("delivery.courier_stale_after", d.Delivery.CourierStaleAfter, "age after which courier locations are stale, 0 disables")
+
+	fs.String("pricing.currency", d.Pricing.Currency, "ISO 4217 currency of the fees")
+	fs.Int64("pricing.base_fee", d.Pricing.BaseFee, "fee of every delivery in the minor unit of the currency")
+	fs.Int64("pricing.per_km_fee", d.Pricing.PerKmFee, "fee per kilometer in the minor unit of the currency")
+	fs.Int64("pricing.minimum_fee", d.Pricing.MinimumFee, "minimum fee of a delivery in the minor unit of the currency")
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		_ = fs.SetAnnotation(f.Name, flagAnnotation, []string{"true"})
+	})
+	fs.StringP(FlagConfig, "c", "", "config file (yaml, json or toml), keys are overridden by DCD_* environment variables and flags")
+	flags.AddFlagSet(fs)
+}
+
+// Load reads the configuration from, in increasing precedence, the
+// defaults of the flags, the config file, DCD_* environment variables and
+// the flags set on the command line. It is not validated.
+func Load(flags *pflag.FlagSet) (*Config, error) {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[flagAnnotation]; ok && err == nil {
+			err = v.BindPFlag(f.Name, f)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	path := v.GetString(FlagConfig)
+	if f := flags.Lookup(FlagConfig); f != nil && f.Changed {
+		path = f.Value.String()
+	}
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+	}
+
+	cfg := &Config{}
+	err = v.Unmarshal(cfg, func(dc *mapstructure.DecoderConfig) {
+		// reports misspelled keys of the config file
+		dc.ErrorUnused = true
+		dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(
+			jsonHook,
+			stringToMapHook,
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = cfg.ServiceName
+	}
+	return cfg, nil
+}
+
+// jsonHook decodes JSON strings of environment variables and flags into
+// lists and objects, e.g. DCD_DELIVERY_COURIERS='[{"lat":1,"lng":2}]'
+func jsonHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	s, ok := data.(string)
+	if !ok || from.Kind() != reflect.String {
+		return data, nil
+	}
+	switch to.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Struct:
+	default:
+		return data, nil
+	}
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") && !strings.HasPrefix(s, "{") {
+		return data, nil
+	}
+	var out interface{}
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return out, nil
+}
+
+// stringToMapHook decodes "k1=v1,k2=v2" of environment variables into
+// maps, like the flags of maps
+func stringToMapHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	s, ok := data.(string)
+	if !ok || from.Kind() != reflect.String || to.Kind() != reflect.Map {
+		return data, nil
+	}
+	out := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return out, nil
+	}
+	pairs, err := csv.NewReader(strings.NewReader(s)).Read()
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q must be formatted as key=value", pair)
+		}
+		out[kv[0]] = kv[1]
+	}
+	return out, nil
+}
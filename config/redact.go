@@ -0,0 +1,75 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// Redacted is the placeholder of secrets in Redacted configurations
+const Redacted = "REDACTED"
+
+var (
+	dsnPasswordRegex = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+	dsnUserInfoRegex = regexp.MustCompile(`^([^:@/]+):[^@]*@`)
+)
+
+// Redacted returns a copy of the configuration without secrets, for
+// printing and logging
+func (c Config) Redacted() Config {
+	c.Auth.AdminToken = redact(c.Auth.AdminToken)
+	c.Auth.JWT.Secret = redact(c.Auth.JWT.Secret)
+	if c.Auth.APIKeys != nil {
+		keys := make(map[string]string, len(c.Auth.APIKeys))
+		for client, key := range c.Auth.APIKeys {
+			keys[client] = redact(key)
+		}
+		c.Auth.APIKeys = keys
+	}
+	c.Logging.Sentry.DSN = redactURL(c.Logging.Sentry.DSN)
+	c.DB.DSN = redactDSN(c.DB.DSN)
+	if c.DB.Replicas != nil {
+		replicas := make([]string, len(c.DB.Replicas))
+		for i, dsn := range c.DB.Replicas {
+			replicas[i] = redactDSN(dsn)
+		}
+		c.DB.Replicas = replicas
+	}
+	return c
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// redactURL hides the user info of a URL, e.g. the key of a Sentry DSN
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redact(s)
+	}
+	if u.User != nil {
+		u.User = url.User(Redacted)
+	}
+	return u.String()
+}
+
+// redactDSN hides the password of a URL, key=value or user:password@
+// connection string
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.Host != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+		}
+		q := u.Query()
+		if q.Get("password") != "" {
+			q.Set("password", Redacted)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	dsn = dsnUserInfoRegex.ReplaceAllString(dsn, "${1}:"+Redacted+"@")
+	return dsnPasswordRegex.ReplaceAllString(dsn, "${1}"+Redacted)
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/configx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/ratelimit"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tlsx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	ErrRequired        = errors.New("is required")
+	ErrInvalidPort     = errors.New("must be a port between 1 and 65535")
+	ErrNegative        = errors.New("must not be negative")
+	ErrNotPositive     = errors.New("must be positive")
+	ErrUnknownMode     = errors.New("unknown mode")
+	ErrUnknownLevel    = errors.New("unknown level")
+	ErrUnknownEncoding = errors.New("unknown encoding, must be json or console")
+	ErrUnknownSampler  = errors.New("unknown sampler type")
+	ErrFraction        = errors.New("must be between 0 and 1")
+	ErrEmptyAPIKey     = errors.New("empty API key")
+	ErrCORSCredentials = errors.New("credentials can not be allowed for any origin")
+	ErrInvalidLocation = errors.New("invalid coordinates")
+	ErrInvalidCurrency = errors.New("must be an ISO 4217 code, e.g. EUR")
+	ErrNoAdminAuth     = errors.New("admin authentication is required, e.g. auth.admin_token")
+)
+
+var currencyRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// FieldError is the error of the config key Key, e.g. "server.port"
+type FieldError struct {
+	Key string
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Key + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors are all errors of a configuration
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, "invalid configuration:")
+	for _, fe := range e {
+		lines = append(lines, "  "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Is lets errors.Is match the error of any key
+func (e Errors) Is(target error) bool {
+	for _, fe := range e {
+		if errors.Is(fe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As lets errors.As find the first FieldError, or error of a key, matching
+// target
+func (e Errors) As(target interface{}) bool {
+	for _, fe := range e {
+		if errors.As(fe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Errors) add(key string, err error) {
+	*e = append(*e, &FieldError{Key: key, Err: err})
+}
+
+// Validate checks the configuration is usable, every invalid key is
+// reported in the returned Errors
+func (c *Config) Validate() error {
+	var errs Errors
+
+	if c.ServiceName == "" {
+		errs.add("service_name", ErrRequired)
+	}
+	c.Server.validate(&errs)
+	c.Auth.validate(&errs)
+	if _, err := c.RateLimitPolicy(); err != nil {
+		errs.add("ratelimit", err)
+	}
+	c.Logging.validate(&errs)
+	c.Tracing.validate(&errs)
+	if !c.Metrics.Public && !c.AdminAuthEnabled() {
+		// /metrics would not be served at all
+		errs.add("metrics.public", ErrNoAdminAuth)
+	}
+	c.DB.validate(&errs)
+	c.Delivery.validate(&errs)
+	c.Pricing.validate(&errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (s *Server) validate(errs *Errors) {
+	if s.Port == "" {
+		errs.add("server.port", ErrRequired)
+	} else if p, err := strconv.ParseUint(s.Port, 10, 16); err != nil || p == 0 {
+		errs.add("server.port", fmt.Errorf("%w: %q", ErrInvalidPort, s.Port))
+	}
+	nonNegative(errs, "server.read_timeout", float64(s.ReadTimeout))
+	nonNegative(errs, "server.write_timeout", float64(s.WriteTimeout))
+	nonNegative(errs, "server.shutdown_timeout", float64(s.ShutdownTimeout))
+	nonNegative(errs, "server.shutdown_drain_delay", float64(s.ShutdownDrainDelay))
+	if _, err := httpx.ParseTrustedProxies(s.TrustedProxies); err != nil {
+		errs.add("server.trusted_proxies", err)
+	}
+
+	if s.CORS.AllowCredentials {
+		for _, o := range s.CORS.AllowOrigins {
+			if o == "*" {
+				errs.add("server.cors.allow_credentials", ErrCORSCredentials)
+			}
+		}
+	}
+	nonNegative(errs, "server.cors.max_age", float64(s.CORS.MaxAge))
+	nonNegative(errs, "server.security.hsts_max_age", float64(s.Security.HSTSMaxAge))
+
+	if s.TLS.Enabled() {
+		if err := s.TLS.Config().Validate(); err != nil {
+			errs.add("server.tls", err)
+		}
+	}
+	nonNegative(errs, "server.tls.reload_interval", float64(s.TLS.ReloadInterval))
+}
+
+func (a *Auth) validate(errs *Errors) {
+	clients := make([]string, 0, len(a.APIKeys))
+	for client := range a.APIKeys {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	for _, client := range clients {
+		if a.APIKeys[client] == "" {
+			errs.add("auth.api_keys."+client, ErrEmptyAPIKey)
+		}
+	}
+	nonNegative(errs, "auth.device.token_ttl", float64(a.Device.TokenTTL))
+	nonNegative(errs, "auth.device.rotation_grace", float64(a.Device.RotationGrace))
+}
+
+func (l *Logging) validate(errs *Errors) {
+	if !configx.IsMode(l.Mode) {
+		errs.add("logging.mode", fmt.Errorf("%w: %q", ErrUnknownMode, l.Mode))
+	}
+	if l.Level != "" {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(l.Level)); err != nil {
+			errs.add("logging.level", fmt.Errorf("%w: %q", ErrUnknownLevel, l.Level))
+		}
+	}
+	switch l.Encoding {
+	case "", "json", "console":
+	default:
+		errs.add("logging.encoding", fmt.Errorf("%w: %q", ErrUnknownEncoding, l.Encoding))
+	}
+	if l.File.Path != "" {
+		nonNegative(errs, "logging.file.max_size_mb", float64(l.File.MaxSizeMB))
+		nonNegative(errs, "logging.file.max_backups", float64(l.File.MaxBackups))
+		nonNegative(errs, "logging.file.max_age_days", float64(l.File.MaxAgeDays))
+	}
+	if l.Request.SampleRate < 0 || l.Request.SampleRate > 1 {
+		errs.add("logging.request.sample_rate", ErrFraction)
+	}
+}
+
+func (t *Tracing) validate(errs *Errors) {
+	switch t.Exporter {
+	case "", tracing.ExporterNoop, tracing.ExporterMemory, tracing.ExporterOTLP,
+		tracing.ExporterStdout, tracing.ExporterFile:
+	default:
+		errs.add("tracing.exporter", fmt.Errorf("%w: %q", tracing.ErrUnsupportedExporter, t.Exporter))
+	}
+	if t.Exporter == tracing.ExporterFile && t.Endpoint == "" {
+		errs.add("tracing.endpoint", ErrRequired)
+	}
+	switch t.SamplerType {
+	case "const", "remote", "ratelimiting":
+	case "probabilistic":
+		if t.SamplerParam < 0 || t.SamplerParam > 1 {
+			errs.add("tracing.sampler_param", ErrFraction)
+		}
+	default:
+		errs.add("tracing.sampler_type", fmt.Errorf("%w: %q", ErrUnknownSampler, t.SamplerType))
+	}
+}
+
+func (d *DB) validate(errs *Errors) {
+	if d.DSN != "" && d.Driver == "" {
+		errs.add("db.driver", ErrRequired)
+	}
+	if len(d.Replicas) > 0 && d.DSN == "" {
+		errs.add("db.dsn", ErrRequired)
+	}
+	for i, dsn := range d.Replicas {
+		if dsn == "" {
+			errs.add(fmt.Sprintf("db.replicas[%d]", i), ErrRequired)
+		}
+	}
+	nonNegative(errs, "db.replica_max_lag", float64(d.ReplicaMaxLag))
+	nonNegative(errs, "db.replica_check_interval", float64(d.ReplicaCheckInterval))
+	if len(d.Replicas) > 0 && d.ReplicaCheckInterval == 0 {
+		// replicas which are never checked would be read however far
+		// behind they are
+		errs.add("db.replica_check_interval", ErrNotPositive)
+	}
+	nonNegative(errs, "db.slow_query", float64(d.SlowQuery))
+}
+
+func (d *Delivery) validate(errs *Errors) {
+	if !d.Source.Valid() {
+		errs.add("delivery.source", ErrInvalidLocation)
+	}
+	for i, loc := range d.Couriers {
+		if !loc.Valid() {
+			errs.add(fmt.Sprintf("delivery.couriers[%d]", i), ErrInvalidLocation)
+		}
+	}
+	nonNegative(errs, "delivery.courier_stale_after", float64(d.CourierStaleAfter))
+}
+
+func (p *Pricing) validate(errs *Errors) {
+	if p.Currency != "" && !currencyRegex.MatchString(p.Currency) {
+		errs.add("pricing.currency", fmt.Errorf("%w: %q", ErrInvalidCurrency, p.Currency))
+	}
+	nonNegative(errs, "pricing.base_fee", float64(p.BaseFee))
+	nonNegative(errs, "pricing.per_km_fee", float64(p.PerKmFee))
+	nonNegative(errs, "pricing.minimum_fee", float64(p.MinimumFee))
+}
+
+func nonNegative(errs *Errors, key string, v float64) {
+	if v < 0 || math.IsNaN(v) {
+		errs.add(key, ErrNegative)
+	}
+}
+
+// Valid reports whether the coordinates are in range
+func (l Location) Valid() bool {
+	return validation.IsLatitudeValid(l.Lat) && validation.IsLongitudeValid(l.Lng)
+}
+
+// RateLimitPolicy parses the rate limits
+func (c *Config) RateLimitPolicy() (ratelimit.Policy, error) {
+	def, err := ratelimit.ParseLimit(c.RateLimit.Default)
+	if err != nil {
+		return ratelimit.Policy{}, err
+	}
+	policy := ratelimit.Policy{
+		Default:    def,
+		Routes:     make(map[string]ratelimit.Limit, len(c.RateLimit.Routes)),
+		DailyQuota: c.RateLimit.DailyQuota,
+	}
+	for route, spec := range c.RateLimit.Routes {
+		// keys of config files are lower cased
+		if parts := strings.SplitN(route, " ", 2); len(parts) == 2 {
+			route = strings.ToUpper(parts[0]) + " " + parts[1]
+		}
+		if policy.Routes[route], err = ratelimit.ParseLimit(spec); err != nil {
+			return ratelimit.Policy{}, fmt.Errorf("route %s: %w", route, err)
+		}
+	}
+	return policy, nil
+}
+
+// AdminAuthEnabled reports whether any authentication of admin clients is
+// configured: the admin token, API keys, JWTs or client certificates
+func (c *Config) AdminAuthEnabled() bool {
+	return c.Auth.AdminToken != "" || len(c.Auth.APIKeys) > 0 ||
+		c.Auth.JWT.Secret != "" || c.Auth.JWT.JWKSFile != "" ||
+		(c.Server.TLS.Enabled() && c.Server.TLS.ClientCAFile != "")
+}
+
+// Enabled reports whether the server listens with HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+func (t TLS) Config() tlsx.Config {
+	return tlsx.Config{
+		CertFile:     t.CertFile,
+		KeyFile:      t.KeyFile,
+		ClientCAFile: t.ClientCAFile,
+		ClientAuth:   t.ClientAuth,
+	}
+}
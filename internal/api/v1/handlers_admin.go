@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+const (
+	invalidDurationCode = "invalid_duration"
+	invalidLevelCode    = "invalid_level"
+)
+
+type setLogLevelRequest struct {
+	// Logger is the name of the logger, empty for the base level
+	Logger string `json:"logger"`
+	Level  string `json:"level" validate:"required"`
+	// Duration after which the level is reverted, e.g. "10m"
+	Duration string `json:"duration"`
+}
+
+func (h *Handler) makeGetLogLevelHandler() func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, errorx.Success{Message: "Success Message", Details: h.logger.Levels()})
+	}
+}
+
+func (h *Handler) makeSetLogLevelHandler() func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		var req setLogLevelRequest
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		if err := c.Validate(&req); err != nil {
+			return err
+		}
+
+		var ttl time.Duration
+		if req.Duration != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.Duration); err != nil {
+				return errorx.FromValidation(validation.NewResult().AddFieldError("duration", validation.ErrorDetails{
+					Message: err.Error(),
+					Code:    invalidDurationCode,
+				}))
+			}
+		}
+
+		if err := h.logger.SetLevel(req.Logger, req.Level, ttl); err != nil {
+			return errorx.FromValidation(validation.NewResult().AddFieldError("level", validation.ErrorDetails{
+				Message: err.Error(),
+				Code:    invalidLevelCode,
+			}))
+		}
+		h.logger.Info("log level changed",
+			loggerx.String("name", req.Logger),
+			loggerx.String("level", req.Level),
+			loggerx.String("duration", req.Duration),
+			loggerx.String("client_ip", c.RealIP()),
+		)
+
+		return c.JSON(http.StatusOK, errorx.Success{Message: "Success Message", Details: h.logger.Levels()})
+	}
+}
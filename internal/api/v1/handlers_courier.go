@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/services/delivery"
+	"github.com/labstack/echo/v4"
+)
+
+type updateLocationRequest struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+	// RecordedAt is the time the device took the location, now if empty
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func (r *updateLocationRequest) Validate() *validation.Result {
+	return validation.ValidatePoint("", validation.Point{Lat: r.Lat, Lng: r.Lng})
+}
+
+type issueDeviceTokenResponse struct {
+	Token      string                `json:"token"`
+	Credential auth.DeviceCredential `json:"credential"`
+}
+
+// makeUpdateLocationHandler records the location of a courier. Devices may
+// only report the location of their courier, other clients need the
+// couriers:write scope.
+func (h *Handler) makeUpdateLocationHandler(deliveryService delivery.UseService) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		courierID := c.Param("id")
+		if !canWriteCourier(auth.FromContext(c.Request().Context()), courierID) {
+			return errorx.ErrForbidden
+		}
+
+		var req updateLocationRequest
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		if err := errorx.FromValidation(validation.Validate(&req)); err != nil {
+			return err
+		}
+		// device clocks drift, reports from the future are recorded as now
+		if now := time.Now(); req.RecordedAt.IsZero() || req.RecordedAt.After(now) {
+			req.RecordedAt = now
+		}
+
+		err := deliveryService.UpdateLocation(c.Request().Context(), delivery.DeliverManLocation{
+			CourierID: courierID,
+			Lat:       req.Lat,
+			Lng:       req.Lng,
+			UpdatedAt: req.RecordedAt,
+		})
+		if err != nil {
+			return errorx.ErrBadRequest.Wrap(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// makeListCouriersHandler returns the known courier locations
+func (h *Handler) makeListCouriersHandler(deliveryService delivery.UseService) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		couriers, err := deliveryService.Couriers(c.Request().Context())
+		if err != nil {
+			return errorx.ErrInternal.Wrap(err)
+		}
+		return c.JSON(http.StatusOK, errorx.Success{Message: "Success Message", Details: couriers})
+	}
+}
+
+func canWriteCourier(p *auth.Principal, courierID string) bool {
+	if p == nil {
+		return false
+	}
+	if p.Method == auth.MethodDevice {
+		return p.Subject == courierID
+	}
+	return p.HasScope(auth.ScopeCouriersWrite)
+}
+
+// makeIssueDeviceTokenHandler issues a token for a courier device, the
+// previous token of the device is rotated out after a grace period
+func (h *Handler) makeIssueDeviceTokenHandler(devices *auth.Devices) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		token, cred, err := devices.Issue(c.Request().Context(), c.Param("id"), c.Param("device"))
+		if err != nil {
+			return errorx.ErrBadRequest.Wrap(err)
+		}
+		h.logger.Info("device token issued",
+			loggerx.String("courier_id", cred.CourierID),
+			loggerx.String("device_id", cred.DeviceID),
+			loggerx.String("client_ip", c.RealIP()),
+		)
+		return c.JSON(http.StatusCreated, errorx.Success{Message: "Success Message", Details: issueDeviceTokenResponse{Token: token, Credential: cred}})
+	}
+}
+
+// makeRevokeDeviceHandler revokes the tokens of a device, or of all devices
+// of a courier when the device is not in the path
+func (h *Handler) makeRevokeDeviceHandler(devices *auth.Devices) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		n, err := devices.Revoke(c.Request().Context(), c.Param("id"), c.Param("device"))
+		if err != nil {
+			return err
+		}
+		h.logger.Info("device tokens revoked",
+			loggerx.String("courier_id", c.Param("id")),
+			loggerx.String("device_id", c.Param("device")),
+			loggerx.Int("count", n),
+			loggerx.String("client_ip", c.RealIP()),
+		)
+		return c.JSON(http.StatusOK, errorx.Success{Message: "Success Message", Details: map[string]int{"revoked": n}})
+	}
+}
+
+func (h *Handler) makeListDevicesHandler(devices *auth.Devices) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		creds, err := devices.Credentials(c.Request().Context(), c.Param("id"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, errorx.Success{Message: "Success Message", Details: creds})
+	}
+}
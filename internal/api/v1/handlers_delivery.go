@@ -1,10 +1,10 @@
 package v1
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/services/delivery"
 	"github.com/labstack/echo/v4"
@@ -16,27 +16,24 @@ func (h *Handler) makeGetDeliveryHandler(
 ) func(_ echo.Context) error {
 	return func(c echo.Context) error {
 		var err error
-		sp, _ := tracing.CreateSpan(c.Request().Context(), fmt.Sprintf("Push Order To Delivery"))
+		sp, ctx := tracing.CreateSpan(c.Request().Context(), fmt.Sprintf("Push Order To Delivery"))
 		defer sp.Finish()
+		traceID := tracing.TraceID(ctx)
 		defer func() {
 			if err != nil {
 				tracing.LogSpanError(sp, "", err)
 			}
 		}()
 		sou := delivery.SourceLocation{
-			Lat: 55.545454,
-			Lng: 12.5465465,
+			Lat: h.cfg.Delivery.Source.Lat,
+			Lng: h.cfg.Delivery.Source.Lng,
 		}
-		var listLoc []delivery.DeliverManLocation
-
-		err = json.Unmarshal([]byte(h.cfg.DeliverManLoc), &listLoc)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, errorx.Success{Code: errorx.CodeError(errorx.ErrCalculate), Message: errorx.ErrCalculate.Error()})
-		}
-		res := deliveryService.GetDistance(sou, listLoc)
+		listLoc, err := deliveryService.Couriers(ctx)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, errorx.Success{Code: errorx.CodeError(errorx.ErrCalculate), Message: errorx.ErrCalculate.Error()})
+			h.logger.With(loggerx.TraceID(traceID)).Error("failed to load courier locations", loggerx.Error(err))
+			return errorx.ErrCalculate.Wrap(err)
 		}
-		return c.JSON(http.StatusOK, errorx.Success{Code: errorx.CodeError(err), Message: "Success Message", Details: res})
+		res := deliveryService.GetDistance(ctx, sou, listLoc)
+		return c.JSON(http.StatusOK, errorx.Success{Code: errorx.CodeError(err), Message: "Success Message", Details: res, TraceID: traceID})
 	}
 }
@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/health"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func (h *Handler) makeLivenessHandler() func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, health.Report{Status: health.StatusUp})
+	}
+}
+
+func (h *Handler) makeHealthHandler(checker *health.Checker) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		return healthResponse(c, checker.Health(c.Request().Context()))
+	}
+}
+
+func (h *Handler) makeReadinessHandler(checker *health.Checker) func(_ echo.Context) error {
+	return func(c echo.Context) error {
+		return healthResponse(c, checker.Ready(c.Request().Context()))
+	}
+}
+
+func healthResponse(c echo.Context, report health.Report) error {
+	if report.Status != health.StatusUp {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
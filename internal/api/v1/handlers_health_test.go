@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/health"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	var (
+		ready      bool
+		requiredUp bool
+	)
+	checker := health.New(health.WithReadiness(func() bool { return ready }))
+	checker.Register("database", func(_ context.Context) error {
+		if requiredUp {
+			return nil
+		}
+		return errors.New("connection refused")
+	})
+	checker.RegisterOptional("tracing", func(_ context.Context) error {
+		return errors.New("collector unreachable")
+	})
+
+	h := &Handler{}
+	e := echo.New()
+	e.GET("/livez", h.makeLivenessHandler())
+	e.GET("/healthz", h.makeHealthHandler(checker))
+	e.GET("/readyz", h.makeReadinessHandler(checker))
+
+	get := func(path string) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return rec.Code, report
+	}
+
+	tests := []struct {
+		name       string
+		ready      bool
+		requiredUp bool
+		healthz    int
+		readyz     int
+	}{
+		{name: "healthy", ready: true, requiredUp: true, healthz: http.StatusOK, readyz: http.StatusOK},
+		{name: "required check down", ready: true, healthz: http.StatusServiceUnavailable, readyz: http.StatusServiceUnavailable},
+		{name: "shutting down", requiredUp: true, healthz: http.StatusOK, readyz: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, requiredUp = tt.ready, tt.requiredUp
+
+			code, report := get("/livez")
+			assert.Equal(t, http.StatusOK, code, "liveness does not run checks")
+			assert.Empty(t, report.Checks)
+
+			code, report = get("/healthz")
+			assert.Equal(t, tt.healthz, code)
+			assert.Equal(t, health.StatusDown, report.Checks["tracing"].Status, "optional checks are reported")
+
+			code, _ = get("/readyz")
+			assert.Equal(t, tt.readyz, code)
+		})
+	}
+}
@@ -1,9 +1,75 @@
 package v1
 
+import (
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/metrics"
+	httpx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/http"
+	"github.com/labstack/echo/v4"
+)
+
 func (s *Server) initRoutes() {
+	s.GET("/livez", s.handler.makeLivenessHandler())
+	s.GET("/healthz", s.handler.makeHealthHandler(s.health))
+	s.GET("/readyz", s.handler.makeReadinessHandler(s.health))
 
-	apiV1 := s.Group("/api/v1")
+	authLogger := s.logger.Named("auth")
+	// devices only authenticate location updates, they are granted no scope
+	apiAuth := append([]auth.Authenticator{s.ss.devices}, s.authenticators...)
+	var apiMiddlewares, ingestMiddlewares []echo.MiddlewareFunc
+	if len(s.authenticators) > 0 {
+		apiMiddlewares = append(apiMiddlewares, httpx.Authenticate(authLogger, apiAuth...))
+	} else {
+		// location updates are authenticated even when the API is open
+		ingestMiddlewares = append(ingestMiddlewares, httpx.Authenticate(authLogger, apiAuth...))
+	}
+	if s.limiter != nil {
+		apiMiddlewares = append(apiMiddlewares, httpx.RateLimit(s.logger.Named("ratelimit"), s.limiter))
+	}
+	apiV1 := s.Group("/api/v1", apiMiddlewares...)
 	{
-		apiV1.GET("/list", s.handler.makeGetDeliveryHandler(s.ss.deliveryService))
+		apiV1.GET("/list", s.handler.makeGetDeliveryHandler(s.ss.deliveryService), s.requireScope(auth.ScopeDispatchRead))
+		apiV1.GET("/couriers", s.handler.makeListCouriersHandler(s.ss.deliveryService), s.requireScope(auth.ScopeDispatchRead))
+		apiV1.POST("/couriers/:id/location", s.handler.makeUpdateLocationHandler(s.ss.deliveryService), ingestMiddlewares...)
+	}
+
+	adminAuth := s.authenticators
+	if s.cfg.Auth.AdminToken != "" {
+		admin := auth.NewStaticToken(s.cfg.Auth.AdminToken, auth.Principal{Subject: "admin", Scopes: []string{auth.ScopeAdmin}})
+		adminAuth = append([]auth.Authenticator{admin}, adminAuth...)
+	}
+	var adminMiddlewares []echo.MiddlewareFunc
+	if len(adminAuth) > 0 {
+		adminMiddlewares = []echo.MiddlewareFunc{httpx.Authenticate(authLogger, adminAuth...), httpx.RequireScope(auth.ScopeAdmin)}
+	}
+	if s.cfg.Metrics.Public {
+		s.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	} else {
+		// validated to have admin authentication, scrapers send the admin
+		// token
+		s.GET("/metrics", echo.WrapHandler(metrics.Handler()), adminMiddlewares...)
+	}
+
+	if len(adminAuth) == 0 {
+		s.logger.Warn("no admin authentication is configured, the admin API is not served")
+		return
+	}
+	admin := s.Group("/admin", adminMiddlewares...)
+	{
+		admin.GET("/log/level", s.handler.makeGetLogLevelHandler())
+		admin.PUT("/log/level", s.handler.makeSetLogLevelHandler())
+
+		admin.GET("/couriers/:id/devices", s.handler.makeListDevicesHandler(s.ss.devices))
+		admin.POST("/couriers/:id/devices/:device/token", s.handler.makeIssueDeviceTokenHandler(s.ss.devices))
+		admin.DELETE("/couriers/:id/devices/:device", s.handler.makeRevokeDeviceHandler(s.ss.devices))
+		admin.DELETE("/couriers/:id/devices", s.handler.makeRevokeDeviceHandler(s.ss.devices))
+	}
+}
+
+// requireScope checks scopes of authenticated clients, it is a no-op when
+// the API is open
+func (s *Server) requireScope(scopes ...string) echo.MiddlewareFunc {
+	if len(s.authenticators) == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
 	}
+	return httpx.RequireScope(scopes...)
 }
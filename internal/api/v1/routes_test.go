@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/health"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	httpx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/http"
+)
+
+func TestMetricsRoute(t *testing.T) {
+	get := func(t *testing.T, cfg *config.Config, token string) int {
+		t.Helper()
+		e := echo.New()
+		e.HTTPErrorHandler = httpx.ErrorHandler(loggerx.NewTestLogger())
+		_, err := NewServer(e, cfg, loggerx.NewTestLogger(), health.New(), nil, nil, nil)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("public by default", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(t, config.Default(), ""))
+	})
+
+	t.Run("admin only", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Metrics.Public = false
+		cfg.Auth.AdminToken = "admin-secret"
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, http.StatusUnauthorized, get(t, cfg, ""))
+		assert.Equal(t, http.StatusOK, get(t, cfg, "admin-secret"))
+	})
+}
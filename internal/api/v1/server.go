@@ -2,7 +2,10 @@ package v1
 
 import (
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/health"
 	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/ratelimit"
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/services/delivery"
 
 	"github.com/labstack/echo/v4"
@@ -14,11 +17,17 @@ type Server struct {
 	logger  *loggerx.Logger
 	ss      *ServiceStorage
 	cfg     *config.Config
+	health  *health.Checker
 	handler Handler
+
+	authenticators []auth.Authenticator
+	// limiter is nil when rate limiting is disabled
+	limiter *ratelimit.Limiter
 }
 
 type ServiceStorage struct {
 	deliveryService delivery.UseService
+	devices         *auth.Devices
 }
 
 type Handler struct {
@@ -26,23 +35,50 @@ type Handler struct {
 	cfg    *config.Config
 }
 
-func NewServer(router *echo.Echo, cfg *config.Config, logger *loggerx.Logger) (*Server, error) {
+// NewServer registers the routes on router. The v1 API is protected by
+// authenticators, it is open if there is none. Courier locations are kept
+// in memory when geo is nil, device credentials when devices is nil.
+func NewServer(router *echo.Echo, cfg *config.Config, logger *loggerx.Logger, checker *health.Checker, authenticators []auth.Authenticator, geo *delivery.GeoRepository, devices auth.DeviceStore) (*Server, error) {
 	var err error
 	s := &Server{
-		Echo:   router,
-		cfg:    cfg,
-		logger: logger,
+		Echo:           router,
+		cfg:            cfg,
+		logger:         logger,
+		health:         checker,
+		authenticators: authenticators,
+	}
+	policy, err := cfg.RateLimitPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if policy.Enabled() {
+		s.limiter = ratelimit.New(ratelimit.NewMemoryStore(), policy)
 	}
-	s.ss = NewServiceStorage(cfg, logger)
-	s.handler = Handler{logger: logger, cfg: cfg}
+	s.ss = NewServiceStorage(cfg, logger, geo, devices)
+	// an empty or stale registry is not a fault of this replica, the
+	// couriers could not report through it if it was taken out of service
+	s.health.RegisterOptional("couriers", s.ss.deliveryService.CheckCouriers)
+	s.handler = Handler{logger: logger.Named("api"), cfg: cfg}
 
 	// routes init
 	s.initRoutes()
 	return s, err
 }
 
-func NewServiceStorage(cfg *config.Config, logger *loggerx.Logger) *ServiceStorage {
+func NewServiceStorage(cfg *config.Config, logger *loggerx.Logger, geo *delivery.GeoRepository, devices auth.DeviceStore) *ServiceStorage {
+	var opts []delivery.Option
+	if geo != nil {
+		opts = append(opts, delivery.WithGeoRepository(geo))
+	}
+	if devices == nil {
+		logger.Warn("device credentials are kept in memory, issued device tokens are lost on restart")
+		devices = auth.NewMemoryDeviceStore()
+	}
 	return &ServiceStorage{
-		deliveryService: delivery.NewDeliveryUseCase(cfg, logger),
+		deliveryService: delivery.NewDeliveryUseCase(cfg, logger.Named("delivery"), opts...),
+		devices: auth.NewDevices(devices,
+			auth.WithTokenTTL(cfg.Auth.Device.TokenTTL),
+			auth.WithRotationGrace(cfg.Auth.Device.RotationGrace),
+		),
 	}
 }
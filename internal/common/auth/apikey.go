@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+)
+
+// APIKeyHeader carries static API keys
+const APIKeyHeader = "X-Api-Key"
+
+// Client is the owner of an API key
+type Client struct {
+	Name   string
+	Key    string
+	Scopes []string
+}
+
+// APIKeys authenticates requests with the X-Api-Key header against a static
+// list of clients
+type APIKeys struct {
+	clients []hashedClient
+}
+
+type hashedClient struct {
+	Client
+	hash [sha256.Size]byte
+}
+
+func NewAPIKeys(clients ...Client) *APIKeys {
+	a := &APIKeys{}
+	for _, c := range clients {
+		a.clients = append(a.clients, hashedClient{Client: c, hash: sha256.Sum256([]byte(c.Key))})
+	}
+	return a
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	// hashes have the same length, so comparing them takes the same time
+	// for every client whatever the length of the keys
+	hash := sha256.Sum256([]byte(key))
+	var found *Client
+	for i := range a.clients {
+		if subtle.ConstantTimeCompare(hash[:], a.clients[i].hash[:]) == 1 {
+			found = &a.clients[i].Client
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Subject: found.Name, Scopes: found.Scopes, Method: MethodAPIKey}, nil
+}
+
+// StaticToken authenticates requests with a fixed bearer token, e.g. the
+// admin token. Other bearer tokens are left to the next authenticator.
+type StaticToken struct {
+	hash      [sha256.Size]byte
+	principal Principal
+}
+
+func NewStaticToken(token string, principal Principal) *StaticToken {
+	principal.Method = MethodToken
+	return &StaticToken{hash: sha256.Sum256([]byte(token)), principal: principal}
+}
+
+func (s *StaticToken) Authenticate(r *http.Request) (*Principal, error) {
+	token, err := httpx.ParseBearerToken(r)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+	hash := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(hash[:], s.hash[:]) != 1 {
+		return nil, ErrNoCredentials
+	}
+	p := s.principal
+	return &p, nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Scopes granted to clients. ScopeAdmin grants every scope.
+const (
+	ScopeCouriersWrite = "couriers:write"
+	ScopeDispatchRead  = "dispatch:read"
+	ScopeAdmin         = "admin"
+)
+
+// Methods of authentication
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	MethodToken  = "token"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request does
+	// not carry its kind of credentials, so the next one can be tried
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator identifies the client of a request
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials if the request carries no
+	// credentials it understands, ErrInvalidCredentials or a wrapping
+	// error if they are rejected.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Principal is an authenticated client
+type Principal struct {
+	// Subject is the client name of an API key or the sub claim of a JWT
+	Subject string
+	Scopes  []string
+	Method  string
+	// DeviceID is the device of a courier authenticated with MethodDevice
+	DeviceID string
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of an authenticated request, nil if the
+// request was not authenticated
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func request(header, value string) *http.Request {
+	r, _ := http.NewRequest(http.MethodGet, "/api/v1/list", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestAPIKeys(t *testing.T) {
+	a := NewAPIKeys(
+		Client{Name: "backoffice", Key: "k1", Scopes: []string{ScopeAdmin}},
+		Client{Name: "dispatcher", Key: "k2", Scopes: []string{ScopeDispatchRead}},
+	)
+
+	p, err := a.Authenticate(request(APIKeyHeader, "k2"))
+	require.NoError(t, err)
+	assert.Equal(t, "dispatcher", p.Subject)
+	assert.True(t, p.HasScope(ScopeDispatchRead))
+	assert.False(t, p.HasScope(ScopeCouriersWrite))
+
+	p, err = a.Authenticate(request(APIKeyHeader, "k1"))
+	require.NoError(t, err)
+	assert.True(t, p.HasScope(ScopeCouriersWrite)) // admin
+
+	_, err = a.Authenticate(request(APIKeyHeader, "k3"))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	_, err = a.Authenticate(request("", ""))
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+}
+
+func TestJWTHS256(t *testing.T) {
+	a, err := NewJWT(JWTConfig{Secret: "secret", Issuer: "dcd"})
+	require.NoError(t, err)
+
+	sign := func(claims jwt.MapClaims, secret string) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		require.NoError(t, err)
+		return "Bearer " + s
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+
+	p, err := a.Authenticate(request("Authorization", sign(jwt.MapClaims{"sub": "42", "iss": "dcd", "exp": exp, "scope": "dispatch:read couriers:write"}, "secret")))
+	require.NoError(t, err)
+	assert.Equal(t, "42", p.Subject)
+	assert.Equal(t, []string{ScopeDispatchRead, ScopeCouriersWrite}, p.Scopes)
+	assert.Equal(t, MethodJWT, p.Method)
+
+	testCases := map[string]jwt.MapClaims{
+		"expired":      {"sub": "42", "iss": "dcd", "exp": time.Now().Add(-time.Minute).Unix()},
+		"wrong issuer": {"sub": "42", "iss": "other", "exp": exp},
+		"no subject":   {"iss": "dcd", "exp": exp},
+		"no expiry":    {"sub": "42", "iss": "dcd"},
+	}
+	for name, claims := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.Authenticate(request("Authorization", sign(claims, "secret")))
+			assert.True(t, errors.Is(err, ErrInvalidCredentials))
+		})
+	}
+
+	_, err = a.Authenticate(request("Authorization", sign(jwt.MapClaims{"sub": "42", "iss": "dcd"}, "other")))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestJWTRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	set := map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	a, err := NewJWT(JWTConfig{JWKSFile: path, Audience: "dcd-api"})
+	require.NoError(t, err)
+
+	sign := func(k *rsa.PrivateKey, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "courier-7", "aud": "dcd-api", "exp": time.Now().Add(time.Minute).Unix(), "roles": []string{ScopeCouriersWrite}})
+		token.Header["kid"] = kid
+		s, err := token.SignedString(k)
+		require.NoError(t, err)
+		return "Bearer " + s
+	}
+
+	p, err := a.Authenticate(request("Authorization", sign(key, "k1")))
+	require.NoError(t, err)
+	assert.Equal(t, "courier-7", p.Subject)
+	assert.True(t, p.HasScope(ScopeCouriersWrite))
+
+	_, err = a.Authenticate(request("Authorization", sign(other, "k1")))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	_, err = a.Authenticate(request("Authorization", sign(key, "k2")))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	// HS256 tokens are rejected when no secret is configured
+	hs, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x", "aud": "dcd-api"}).SignedString([]byte(""))
+	require.NoError(t, err)
+	_, err = a.Authenticate(request("Authorization", "Bearer "+hs))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestClientCerts(t *testing.T) {
+	a := NewClientCerts(map[string][]string{"billing": {ScopeDispatchRead}})
+
+	_, err := a.Authenticate(request("", ""))
+	assert.ErrorIs(t, err, ErrNoCredentials)
+
+	r := request("", "")
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "billing"}}}}}
+	p, err := a.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", p.Subject)
+	assert.Equal(t, MethodClientCert, p.Method)
+	assert.True(t, p.HasScope(ScopeDispatchRead))
+
+	r.TLS.VerifiedChains[0][0].Subject.CommonName = ""
+	_, err = a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
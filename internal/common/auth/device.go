@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+)
+
+const (
+	// MethodDevice authenticates a courier device, the principal subject is
+	// the courier ID
+	MethodDevice = "device"
+
+	// DeviceIDHeader must match the device a token was issued for
+	DeviceIDHeader = "X-Device-Id"
+
+	// deviceTokenPrefix tells device tokens apart from JWTs
+	deviceTokenPrefix = "dcd_dev_"
+
+	defaultRotationGrace = 5 * time.Minute
+)
+
+var (
+	ErrDeviceNotFound = errors.New("device credential not found")
+	ErrDeviceRevoked  = errors.New("device credential revoked")
+	ErrDeviceExpired  = errors.New("device credential expired")
+	ErrDeviceMismatch = errors.New("device does not match the credential")
+	ErrEmptyDeviceIDs = errors.New("courier id and device id are required")
+)
+
+// DeviceCredential binds a device token to a courier and one of its
+// devices. Only the hash of the token is kept.
+type DeviceCredential struct {
+	CourierID string    `json:"courier_id"`
+	DeviceID  string    `json:"device_id"`
+	TokenHash string    `json:"-"`
+	IssuedAt  time.Time `json:"issued_at"`
+	// ExpiresAt is zero for credentials which do not expire
+	ExpiresAt  time.Time `json:"expires_at"`
+	RevokedAt  time.Time `json:"revoked_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	LastIP     string    `json:"last_ip,omitempty"`
+}
+
+// Active reports whether the credential may be used at now
+func (c *DeviceCredential) Active(now time.Time) bool {
+	return c.RevokedAt.IsZero() && (c.ExpiresAt.IsZero() || now.Before(c.ExpiresAt))
+}
+
+// DeviceStore persists device credentials
+type DeviceStore interface {
+	Put(ctx context.Context, cred DeviceCredential) error
+	// Get returns ErrDeviceNotFound for unknown hashes
+	Get(ctx context.Context, tokenHash string) (DeviceCredential, error)
+	// List returns the credentials of a courier
+	List(ctx context.Context, courierID string) ([]DeviceCredential, error)
+	// Touch records the use of a credential without writing its other
+	// fields, so it does not undo a concurrent revocation or rotation
+	Touch(ctx context.Context, tokenHash string, at time.Time, ip string) error
+}
+
+type DeviceOption func(*Devices)
+
+// WithTokenTTL expires device tokens ttl after they were issued, zero
+// keeps them until they are rotated or revoked
+func WithTokenTTL(ttl time.Duration) DeviceOption {
+	return func(d *Devices) {
+		d.ttl = ttl
+	}
+}
+
+// WithRotationGrace keeps the previous token of a device valid for grace
+// after a new one is issued, so in-flight updates are not rejected
+func WithRotationGrace(grace time.Duration) DeviceOption {
+	return func(d *Devices) {
+		d.grace = grace
+	}
+}
+
+// Devices issues device tokens and authenticates location updates with
+// them. The bearer token must be sent with the X-Device-Id header of the
+// device it was issued for.
+type Devices struct {
+	store DeviceStore
+	ttl   time.Duration
+	grace time.Duration
+	now   func() time.Time
+}
+
+func NewDevices(store DeviceStore, opts ...DeviceOption) *Devices {
+	d := &Devices{
+		store: store,
+		grace: defaultRotationGrace,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Issue returns a new token of a device. Previous tokens of the device
+// stay valid for the rotation grace period.
+func (d *Devices) Issue(ctx context.Context, courierID, deviceID string) (string, DeviceCredential, error) {
+	courierID, deviceID = strings.TrimSpace(courierID), strings.TrimSpace(deviceID)
+	if courierID == "" || deviceID == "" {
+		return "", DeviceCredential{}, ErrEmptyDeviceIDs
+	}
+
+	now := d.now()
+	creds, err := d.store.List(ctx, courierID)
+	if err != nil {
+		return "", DeviceCredential{}, err
+	}
+	for _, c := range creds {
+		if c.DeviceID != deviceID || !c.Active(now) {
+			continue
+		}
+		if end := now.Add(d.grace); c.ExpiresAt.IsZero() || end.Before(c.ExpiresAt) {
+			c.ExpiresAt = end
+		}
+		if err := d.store.Put(ctx, c); err != nil {
+			return "", DeviceCredential{}, err
+		}
+	}
+
+	token, err := newDeviceToken()
+	if err != nil {
+		return "", DeviceCredential{}, err
+	}
+	cred := DeviceCredential{
+		CourierID: courierID,
+		DeviceID:  deviceID,
+		TokenHash: hashToken(token),
+		IssuedAt:  now,
+	}
+	if d.ttl > 0 {
+		cred.ExpiresAt = now.Add(d.ttl)
+	}
+	if err := d.store.Put(ctx, cred); err != nil {
+		return "", DeviceCredential{}, err
+	}
+	return token, cred, nil
+}
+
+// Revoke invalidates the tokens of a device at once, or of every device of
+// the courier if deviceID is empty. It returns the number of revoked
+// credentials.
+func (d *Devices) Revoke(ctx context.Context, courierID, deviceID string) (int, error) {
+	now := d.now()
+	creds, err := d.store.List(ctx, courierID)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, c := range creds {
+		if (deviceID != "" && c.DeviceID != deviceID) || !c.RevokedAt.IsZero() {
+			continue
+		}
+		c.RevokedAt = now
+		if err := d.store.Put(ctx, c); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Credentials returns the credentials of a courier
+func (d *Devices) Credentials(ctx context.Context, courierID string) ([]DeviceCredential, error) {
+	return d.store.List(ctx, courierID)
+}
+
+func (d *Devices) Authenticate(r *http.Request) (*Principal, error) {
+	token, err := httpx.ParseBearerToken(r)
+	if err != nil || !strings.HasPrefix(token, deviceTokenPrefix) {
+		return nil, ErrNoCredentials
+	}
+
+	ri := httpx.GetRequestInfo(r)
+	deviceID := strings.TrimSpace(r.Header.Get(DeviceIDHeader))
+	if res := validation.ValidateIPAddressAndDevice(ri.IPAddr, deviceID); !res.IsValid() {
+		return nil, fmt.Errorf("%w: invalid ip address or device", ErrInvalidCredentials)
+	}
+
+	ctx := r.Context()
+	cred, err := d.store.Get(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err.Error())
+	}
+	now := d.now()
+	switch {
+	case !cred.RevokedAt.IsZero():
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, ErrDeviceRevoked.Error())
+	case !cred.Active(now):
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, ErrDeviceExpired.Error())
+	case cred.DeviceID != deviceID:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, ErrDeviceMismatch.Error())
+	}
+
+	if err := d.store.Touch(ctx, cred.TokenHash, now, ri.IPAddr); err != nil {
+		return nil, err
+	}
+
+	return &Principal{Subject: cred.CourierID, DeviceID: cred.DeviceID, Method: MethodDevice}, nil
+}
+
+func newDeviceToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device token: %w", err)
+	}
+	return deviceTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// MemoryDeviceStore keeps device credentials in memory, they are lost on
+// restart
+type MemoryDeviceStore struct {
+	mu    sync.RWMutex
+	creds map[string]DeviceCredential
+}
+
+func NewMemoryDeviceStore() *MemoryDeviceStore {
+	return &MemoryDeviceStore{creds: make(map[string]DeviceCredential)}
+}
+
+func (s *MemoryDeviceStore) Put(_ context.Context, cred DeviceCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creds[cred.TokenHash] = cred
+	return nil
+}
+
+func (s *MemoryDeviceStore) Get(_ context.Context, tokenHash string) (DeviceCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cred, ok := s.creds[tokenHash]
+	if !ok {
+		return DeviceCredential{}, ErrDeviceNotFound
+	}
+	return cred, nil
+}
+
+func (s *MemoryDeviceStore) Touch(_ context.Context, tokenHash string, at time.Time, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.creds[tokenHash]
+	if !ok {
+		return ErrDeviceNotFound
+	}
+	cred.LastUsedAt = at
+	cred.LastIP = ip
+	s.creds[tokenHash] = cred
+	return nil
+}
+
+func (s *MemoryDeviceStore) List(_ context.Context, courierID string) ([]DeviceCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []DeviceCredential
+	for _, c := range s.creds {
+		if c.CourierID == courierID {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].IssuedAt.Before(out[j].IssuedAt)
+	})
+	return out, nil
+}
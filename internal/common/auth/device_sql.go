@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx"
+)
+
+const selectDeviceCredentials = "SELECT token_hash, courier_id, device_id, issued_at, expires_at, revoked_at, last_used_at, last_ip FROM device_credentials"
+
+// SQLDeviceStore keeps device credentials in the migrated
+// device_credentials table, zero times are stored as NULL
+type SQLDeviceStore struct {
+	db dbx.DB
+}
+
+func NewSQLDeviceStore(db dbx.DB) *SQLDeviceStore {
+	return &SQLDeviceStore{db: db}
+}
+
+type deviceCredentialRow struct {
+	TokenHash  string       `db:"token_hash"`
+	CourierID  string       `db:"courier_id"`
+	DeviceID   string       `db:"device_id"`
+	IssuedAt   time.Time    `db:"issued_at"`
+	ExpiresAt  sql.NullTime `db:"expires_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at"`
+	LastUsedAt sql.NullTime `db:"last_used_at"`
+	LastIP     string       `db:"last_ip"`
+}
+
+func (r deviceCredentialRow) credential() DeviceCredential {
+	return DeviceCredential{
+		CourierID:  r.CourierID,
+		DeviceID:   r.DeviceID,
+		TokenHash:  r.TokenHash,
+		IssuedAt:   r.IssuedAt,
+		ExpiresAt:  fromNullTime(r.ExpiresAt),
+		RevokedAt:  fromNullTime(r.RevokedAt),
+		LastUsedAt: fromNullTime(r.LastUsedAt),
+		LastIP:     r.LastIP,
+	}
+}
+
+// Put inserts or updates a credential. The last use is left to Touch, so
+// a credential read before a use does not undo it.
+func (s *SQLDeviceStore) Put(ctx context.Context, cred DeviceCredential) error {
+	db := dbx.Connection(ctx, s.db)
+	_, err := db.ExecContext(ctx, db.Rebind(`INSERT INTO device_credentials (token_hash, courier_id, device_id, issued_at, expires_at, revoked_at, last_used_at, last_ip)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (token_hash) DO UPDATE SET courier_id = excluded.courier_id, device_id = excluded.device_id,
+issued_at = excluded.issued_at, expires_at = excluded.expires_at, revoked_at = excluded.revoked_at`),
+		cred.TokenHash, cred.CourierID, cred.DeviceID, cred.IssuedAt.UTC(),
+		toNullTime(cred.ExpiresAt), toNullTime(cred.RevokedAt), toNullTime(cred.LastUsedAt), cred.LastIP)
+	if err != nil {
+		return fmt.Errorf("failed to store device credential: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLDeviceStore) Get(ctx context.Context, tokenHash string) (DeviceCredential, error) {
+	db := dbx.Connection(ctx, s.db)
+	var row deviceCredentialRow
+	err := db.GetContext(ctx, &row, db.Rebind(selectDeviceCredentials+" WHERE token_hash = ?"), tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DeviceCredential{}, ErrDeviceNotFound
+	}
+	if err != nil {
+		return DeviceCredential{}, fmt.Errorf("failed to load device credential: %w", err)
+	}
+	return row.credential(), nil
+}
+
+func (s *SQLDeviceStore) Touch(ctx context.Context, tokenHash string, at time.Time, ip string) error {
+	db := dbx.Connection(ctx, s.db)
+	res, err := db.ExecContext(ctx, db.Rebind("UPDATE device_credentials SET last_used_at = ?, last_ip = ? WHERE token_hash = ?"),
+		at.UTC(), ip, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to record device credential use: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *SQLDeviceStore) List(ctx context.Context, courierID string) ([]DeviceCredential, error) {
+	db := dbx.Connection(ctx, s.db)
+	var rows []deviceCredentialRow
+	err := db.SelectContext(ctx, &rows, db.Rebind(selectDeviceCredentials+" WHERE courier_id = ? ORDER BY issued_at, token_hash"), courierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device credentials: %w", err)
+	}
+	var out []DeviceCredential
+	for _, r := range rows {
+		out = append(out, r.credential())
+	}
+	return out, nil
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time
+}
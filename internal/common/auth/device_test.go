@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx/migrate"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deviceRequest(token, deviceID string) *http.Request {
+	r := request("Authorization", "Bearer "+token)
+	r.RemoteAddr = "10.0.0.7:52100"
+	r.Header.Set(DeviceIDHeader, deviceID)
+	return r
+}
+
+func TestDevices(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDevices(NewMemoryDeviceStore(), WithRotationGrace(time.Minute))
+	d.now = func() time.Time { return now }
+
+	token, cred, err := d.Issue(ctx, "c1", "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "c1", cred.CourierID)
+
+	p, err := d.Authenticate(deviceRequest(token, "d1"))
+	require.NoError(t, err)
+	assert.Equal(t, "c1", p.Subject)
+	assert.Equal(t, "d1", p.DeviceID)
+	assert.Equal(t, MethodDevice, p.Method)
+	assert.Empty(t, p.Scopes)
+	used, err := d.store.Get(ctx, cred.TokenHash)
+	require.NoError(t, err)
+	assert.Equal(t, now, used.LastUsedAt)
+	assert.Equal(t, "10.0.0.7", used.LastIP)
+
+	// bound to the device
+	_, err = d.Authenticate(deviceRequest(token, "d2"))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	_, err = d.Authenticate(deviceRequest(token, ""))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	// other bearer tokens are left to the next authenticator
+	_, err = d.Authenticate(deviceRequest("eyJhbGciOi.x.y", "d1"))
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+
+	// the previous token is valid during the rotation grace period
+	rotated, _, err := d.Issue(ctx, "c1", "d1")
+	require.NoError(t, err)
+	_, err = d.Authenticate(deviceRequest(token, "d1"))
+	assert.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = d.Authenticate(deviceRequest(token, "d1"))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	_, err = d.Authenticate(deviceRequest(rotated, "d1"))
+	assert.NoError(t, err)
+
+	other, _, err := d.Issue(ctx, "c1", "d2")
+	require.NoError(t, err)
+	n, err := d.Revoke(ctx, "c1", "d1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, err = d.Authenticate(deviceRequest(rotated, "d1"))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	_, err = d.Authenticate(deviceRequest(other, "d2"))
+	assert.NoError(t, err)
+
+	n, err = d.Revoke(ctx, "c1", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, _, err = d.Issue(ctx, " ", "d1")
+	assert.True(t, errors.Is(err, ErrEmptyDeviceIDs))
+}
+
+// interleavedDeviceStore runs afterGet once between the read and the
+// write of a credential by Authenticate
+type interleavedDeviceStore struct {
+	*MemoryDeviceStore
+	afterGet func()
+}
+
+func (s *interleavedDeviceStore) Get(ctx context.Context, tokenHash string) (DeviceCredential, error) {
+	cred, err := s.MemoryDeviceStore.Get(ctx, tokenHash)
+	if f := s.afterGet; f != nil {
+		s.afterGet = nil
+		f()
+	}
+	return cred, err
+}
+
+func TestDevicesRevokeWhileAuthenticating(t *testing.T) {
+	ctx := context.Background()
+	store := &interleavedDeviceStore{MemoryDeviceStore: NewMemoryDeviceStore()}
+	d := NewDevices(store)
+	token, _, err := d.Issue(ctx, "c1", "d1")
+	require.NoError(t, err)
+
+	store.afterGet = func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := d.Revoke(ctx, "c1", "d1")
+			assert.NoError(t, err)
+			assert.Equal(t, 1, n)
+		}()
+		wg.Wait()
+	}
+	_, err = d.Authenticate(deviceRequest(token, "d1"))
+	require.NoError(t, err, "the credential was active when it was read")
+
+	_, err = d.Authenticate(deviceRequest(token, "d1"))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials), "the revocation must not be overwritten")
+	creds, err := d.Credentials(ctx, "c1")
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	assert.False(t, creds[0].RevokedAt.IsZero())
+	assert.Equal(t, "10.0.0.7", creds[0].LastIP)
+}
+
+func newTestSQLDeviceStore(t *testing.T) *SQLDeviceStore {
+	t.Helper()
+	ctx := context.Background()
+	db, err := dbx.Open(ctx, "sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migs, err := migrate.Load(migrations.FS, migrate.SQLite)
+	require.NoError(t, err)
+	m, err := migrate.New(db, migs)
+	require.NoError(t, err)
+	_, err = m.Up(ctx, 0)
+	require.NoError(t, err)
+	return NewSQLDeviceStore(db)
+}
+
+func TestSQLDeviceStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLDeviceStore(t)
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err := store.Get(ctx, "missing")
+	assert.True(t, errors.Is(err, ErrDeviceNotFound))
+	assert.True(t, errors.Is(store.Touch(ctx, "missing", now, "10.0.0.7"), ErrDeviceNotFound))
+
+	cred := DeviceCredential{CourierID: "c1", DeviceID: "d1", TokenHash: "h1", IssuedAt: now}
+	require.NoError(t, store.Put(ctx, cred))
+	got, err := store.Get(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, cred, got, "zero times are kept")
+
+	require.NoError(t, store.Touch(ctx, "h1", now.Add(time.Minute), "10.0.0.7"))
+	// a credential read before the use does not undo it
+	cred.RevokedAt = now.Add(2 * time.Minute)
+	require.NoError(t, store.Put(ctx, cred))
+	got, err = store.Get(ctx, "h1")
+	require.NoError(t, err)
+	assert.True(t, cred.RevokedAt.Equal(got.RevokedAt))
+	assert.True(t, now.Add(time.Minute).Equal(got.LastUsedAt))
+	assert.Equal(t, "10.0.0.7", got.LastIP)
+
+	require.NoError(t, store.Put(ctx, DeviceCredential{CourierID: "c1", DeviceID: "d2", TokenHash: "h0", IssuedAt: now.Add(time.Hour)}))
+	require.NoError(t, store.Put(ctx, DeviceCredential{CourierID: "c2", DeviceID: "d1", TokenHash: "h2", IssuedAt: now}))
+	creds, err := store.List(ctx, "c1")
+	require.NoError(t, err)
+	require.Len(t, creds, 2)
+	assert.Equal(t, "h1", creds[0].TokenHash, "ordered by issue time")
+	assert.Equal(t, "h0", creds[1].TokenHash)
+
+	// the credentials survive a new Devices, e.g. after a restart
+	d := NewDevices(store)
+	token, _, err := d.Issue(ctx, "c3", "d1")
+	require.NoError(t, err)
+	p, err := NewDevices(store).Authenticate(deviceRequest(token, "d1"))
+	require.NoError(t, err)
+	assert.Equal(t, "c3", p.Subject)
+}
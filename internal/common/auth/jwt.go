@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+)
+
+var (
+	ErrJWTNotConfigured = errors.New("neither a JWT secret nor a JWKS file is configured")
+	ErrUnknownKey       = errors.New("unknown signing key")
+)
+
+type JWTConfig struct {
+	// Secret verifies HS256 tokens
+	Secret string
+	// JWKSFile holds the public keys verifying RS256 tokens
+	JWKSFile string
+	// Issuer and Audience are checked when set
+	Issuer   string
+	Audience string
+}
+
+// JWT authenticates requests with a bearer JWT signed with HS256 or RS256.
+// The subject is read from the sub claim and the scopes from the space
+// separated scope claim or the scp, scopes or roles arrays.
+type JWT struct {
+	cfg    JWTConfig
+	parser *jwt.Parser
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+}
+
+func NewJWT(cfg JWTConfig) (*JWT, error) {
+	var methods []string
+	if cfg.Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, ErrJWTNotConfigured
+	}
+
+	j := &JWT{
+		cfg:    cfg,
+		parser: jwt.NewParser(jwt.WithValidMethods(methods)),
+	}
+	if err := j.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Reload reads the JWKS file again, e.g. after keys have been rotated. The
+// current keys are kept if the file is invalid.
+func (j *JWT) Reload(_ context.Context) error {
+	if j.cfg.JWKSFile == "" {
+		return nil
+	}
+	keys, err := LoadJWKS(j.cfg.JWKSFile)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	raw, err := httpx.ParseBearerToken(r)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := j.parser.ParseWithClaims(raw, claims, j.key); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err.Error())
+	}
+	// the parser only checks exp when it is set, tokens must expire
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("%w: missing or expired exp claim", ErrInvalidCredentials)
+	}
+	if j.cfg.Issuer != "" && !claims.VerifyIssuer(j.cfg.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if j.cfg.Audience != "" && !claims.VerifyAudience(j.cfg.Audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidCredentials)
+	}
+
+	return &Principal{Subject: sub, Scopes: scopes(claims), Method: MethodJWT}, nil
+}
+
+func (j *JWT) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return []byte(j.cfg.Secret), nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		j.mu.RLock()
+		defer j.mu.RUnlock()
+		if key, ok := j.keys[kid]; ok {
+			return key, nil
+		}
+		// tokens without kid are accepted when there is a single key
+		if kid == "" && len(j.keys) == 1 {
+			for _, key := range j.keys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, token.Method.Alg())
+	}
+}
+
+func scopes(claims jwt.MapClaims) []string {
+	if s, ok := claims["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	var out []string
+	for _, name := range []string{"scp", "scopes", "roles"} {
+		list, ok := claims[name].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
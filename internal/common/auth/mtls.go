@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// MethodClientCert authenticates a service with a verified TLS client
+// certificate, the principal subject is the common name
+const MethodClientCert = "client_cert"
+
+// ClientCerts authenticates callers presenting a client certificate
+// verified by the TLS server, e.g. other services over mTLS
+type ClientCerts struct {
+	scopes map[string][]string
+}
+
+// NewClientCerts grants scopes to common names, callers with other names
+// are authenticated without scope
+func NewClientCerts(scopes map[string][]string) *ClientCerts {
+	return &ClientCerts{scopes: scopes}
+}
+
+func (a *ClientCerts) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if cn == "" {
+		return nil, fmt.Errorf("%w: client certificate without common name", ErrInvalidCredentials)
+	}
+	return &Principal{Subject: cn, Scopes: a.scopes[cn], Method: MethodClientCert}, nil
+}
@@ -6,3 +6,13 @@ const (
 	ModeStage = "stage"
 	ModeProd  = "production"
 )
+
+// IsMode reports whether mode is one of the known modes
+func IsMode(mode string) bool {
+	switch mode {
+	case ModeLocal, ModeDev, ModeStage, ModeProd:
+		return true
+	default:
+		return false
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/jmoiron/sqlx"
@@ -52,39 +54,116 @@ func JsonbToBytes(jsonb Jsonb) []byte {
 
 type txKey struct{}
 
+// txState is the transaction of a context, depth counts the savepoints of
+// the nested transactions
+type txState struct {
+	tx    *sqlx.Tx
+	depth int
+}
+
 // withTx assign Tx to the context
 func withTx(ctx context.Context, tx *sqlx.Tx) context.Context {
-	return context.WithValue(ctx, txKey{}, tx)
+	return context.WithValue(ctx, txKey{}, &txState{tx: tx})
 }
 
-// Connection get connection (*sqlx.db | *sqlx.tx) from the context
+// Connection get connection (*sqlx.db | *sqlx.tx) from the context, the
+// transaction is wrapped like db when db is a wrapper such as Instrumented
 func Connection(ctx context.Context, db DB) DB {
 	tx, ok := txFromContext(ctx)
-	if ok {
-		return tx
+	if !ok {
+		return db
+	}
+	if w, ok := db.(wrapper); ok {
+		return w.wrapTx(tx)
+	}
+	return tx
+}
+
+// sqlxDB returns the *sqlx.DB wrapped by db
+func sqlxDB(db DB) (*sqlx.DB, bool) {
+	for {
+		switch d := db.(type) {
+		case *sqlx.DB:
+			return d, true
+		case wrapper:
+			db = d.Unwrap()
+		default:
+			return nil, false
+		}
 	}
-	return db
 }
 
 func txFromContext(ctx context.Context) (*sqlx.Tx, bool) {
-	tx, ok := ctx.Value(txKey{}).(*sqlx.Tx)
-	return tx, ok
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
 }
 
-// Transactional run wrapped func in transaction
-func Transactional(ctx context.Context, db *sqlx.DB, wrappedFunc func(ctx context.Context) error) (err error) {
-	tx, ctxWithTx, err := BeginTx(ctx, db)
+// Transactional run wrapped func in transaction. When the context already
+// has a transaction, the func runs in a savepoint of it which is rolled
+// back on error without aborting the outer transaction. The transaction is
+// rolled back and the panic propagated when the func panics.
+//
+// Transactions which fail with a serialization failure or a deadlock can be
+// retried with backoff by opting in with WithRetries. Nested transactions are never
+// retried on their own, the error reaches the outermost one which retries
+// the whole func.
+// Error ErrDBType
+func Transactional(ctx context.Context, db DB, wrappedFunc func(ctx context.Context) error, opts ...TxOption) error {
+	o := defaultTxOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return savepoint(ctx, state, wrappedFunc)
+	}
+
+	dbt, ok := sqlxDB(db)
+	if !ok {
+		return ErrDBType
+	}
+	backoff := o.backoff
+	for attempt := 0; ; attempt++ {
+		err := transactional(ctx, dbt, &o, wrappedFunc)
+		if err == nil || attempt >= o.retries || !IsRetryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(jitter(backoff)):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func transactional(ctx context.Context, db *sqlx.DB, o *txOptions, wrappedFunc func(ctx context.Context) error) (err error) {
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{Isolation: o.isolation, ReadOnly: o.readOnly})
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 
 	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
 		if err != nil {
 			_ = tx.Rollback()
 		}
 	}()
 
-	err = wrappedFunc(ctxWithTx)
+	err = wrappedFunc(withTx(ctx, tx))
 	if err != nil {
 		return err
 	}
@@ -93,6 +172,35 @@ func Transactional(ctx context.Context, db *sqlx.DB, wrappedFunc func(ctx contex
 	return err
 }
 
+// savepoint runs wrappedFunc in a savepoint of the transaction of state
+func savepoint(ctx context.Context, state *txState, wrappedFunc func(ctx context.Context) error) (err error) {
+	name := fmt.Sprintf("dbx_savepoint_%d", state.depth+1)
+	if _, err = state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = state.tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+		if err != nil {
+			// the savepoint is kept after the rollback, release it as well
+			_, _ = state.tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+name)
+			_, _ = state.tx.ExecContext(context.Background(), "RELEASE SAVEPOINT "+name)
+		}
+	}()
+
+	nested := &txState{tx: state.tx, depth: state.depth + 1}
+	if err = wrappedFunc(context.WithValue(ctx, txKey{}, nested)); err != nil {
+		return err
+	}
+	if _, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
 // BeginTx start transaction
 // Error ErrTransactionStarted
 // Error ErrDBType
@@ -102,7 +210,7 @@ func BeginTx(ctx context.Context, db DB) (*sqlx.Tx, context.Context, error) {
 		return tx, ctx, ErrTransactionStarted
 	}
 
-	dbt, ok := db.(*sqlx.DB)
+	dbt, ok := sqlxDB(db)
 	if !ok {
 		return nil, ctx, ErrDBType
 	}
@@ -114,3 +222,23 @@ func BeginTx(ctx context.Context, db DB) (*sqlx.Tx, context.Context, error) {
 
 	return tx, withTx(ctx, tx), nil
 }
+
+// Open opens and verifies a connection to the database
+func Open(ctx context.Context, driverName, dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", driverName, err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping %s database: %w", driverName, err)
+	}
+	return db, nil
+}
+
+// Ping checks the database is reachable, it is suitable as a health check
+func Ping(db *sqlx.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
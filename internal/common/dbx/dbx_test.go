@@ -0,0 +1,256 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTest = errors.New("test error")
+
+func openSQLite(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := Open(context.Background(), "sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	_, err = db.Exec("CREATE TABLE couriers (id TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+	return db
+}
+
+func insert(ctx context.Context, db DB, id string) error {
+	_, err := Connection(ctx, db).ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", id)
+	return err
+}
+
+func ids(t *testing.T, db *sqlx.DB) []string {
+	t.Helper()
+	var out []string
+	require.NoError(t, db.Select(&out, "SELECT id FROM couriers ORDER BY id"))
+	return out
+}
+
+func TestTransactional(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context, db *sqlx.DB) error
+		err  error
+		ids  []string
+	}{
+		{
+			name: "commit",
+			fn: func(ctx context.Context, db *sqlx.DB) error {
+				return insert(ctx, db, "a")
+			},
+			ids: []string{"a"},
+		},
+		{
+			name: "rollback",
+			fn: func(ctx context.Context, db *sqlx.DB) error {
+				if err := insert(ctx, db, "a"); err != nil {
+					return err
+				}
+				return errTest
+			},
+			err: errTest,
+		},
+		{
+			name: "nested commit",
+			fn: func(ctx context.Context, db *sqlx.DB) error {
+				if err := insert(ctx, db, "a"); err != nil {
+					return err
+				}
+				return Transactional(ctx, db, func(ctx context.Context) error {
+					return insert(ctx, db, "b")
+				})
+			},
+			ids: []string{"a", "b"},
+		},
+		{
+			name: "nested rollback keeps outer transaction",
+			fn: func(ctx context.Context, db *sqlx.DB) error {
+				if err := insert(ctx, db, "a"); err != nil {
+					return err
+				}
+				err := Transactional(ctx, db, func(ctx context.Context) error {
+					if err := insert(ctx, db, "b"); err != nil {
+						return err
+					}
+					return Transactional(ctx, db, func(ctx context.Context) error {
+						return insert(ctx, db, "c")
+					})
+				})
+				if err != nil {
+					return err
+				}
+				err = Transactional(ctx, db, func(ctx context.Context) error {
+					if err := insert(ctx, db, "d"); err != nil {
+						return err
+					}
+					return errTest
+				})
+				if !errors.Is(err, errTest) {
+					return fmt.Errorf("unexpected error: %v", err)
+				}
+				return insert(ctx, db, "e")
+			},
+			ids: []string{"a", "b", "c", "e"},
+		},
+		{
+			name: "outer rollback reverts nested commit",
+			fn: func(ctx context.Context, db *sqlx.DB) error {
+				err := Transactional(ctx, db, func(ctx context.Context) error {
+					return insert(ctx, db, "a")
+				})
+				if err != nil {
+					return err
+				}
+				return errTest
+			},
+			err: errTest,
+		},
+		{
+			name: "begin in transaction",
+			fn: func(ctx context.Context, db *sqlx.DB) error {
+				_, _, err := BeginTx(ctx, db)
+				return err
+			},
+			err: ErrTransactionStarted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openSQLite(t)
+			err := Transactional(context.Background(), db, func(ctx context.Context) error {
+				return tt.fn(ctx, db)
+			})
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.ids, ids(t, db))
+		})
+	}
+}
+
+func TestTransactionalPanic(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = Transactional(ctx, db, func(ctx context.Context) error {
+			_ = insert(ctx, db, "a")
+			panic("boom")
+		})
+	})
+	assert.Empty(t, ids(t, db))
+
+	err := Transactional(ctx, db, func(ctx context.Context) error {
+		if err := insert(ctx, db, "a"); err != nil {
+			return err
+		}
+		assert.Panics(t, func() {
+			_ = Transactional(ctx, db, func(ctx context.Context) error {
+				_ = insert(ctx, db, "b")
+				panic("boom")
+			})
+		})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(t, db))
+}
+
+func TestTransactionalRetry(t *testing.T) {
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+	tests := []struct {
+		name     string
+		opts     []TxOption
+		failures int
+		err      error
+		attempts int
+	}{
+		{name: "no failure", attempts: 1},
+		{name: "retried", failures: 2, attempts: 3},
+		{name: "retries exhausted", failures: 5, err: busy, attempts: 4},
+		{name: "retries disabled", opts: []TxOption{WithRetries(0, 0)}, failures: 1, err: busy, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openSQLite(t)
+			attempts := 0
+			opts := append([]TxOption{WithRetries(3, time.Millisecond)}, tt.opts...)
+			err := Transactional(context.Background(), db, func(ctx context.Context) error {
+				attempts++
+				if err := insert(ctx, db, "a"); err != nil {
+					return err
+				}
+				if attempts <= tt.failures {
+					return fmt.Errorf("failed to update: %w", busy)
+				}
+				return nil
+			}, opts...)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.attempts, attempts)
+		})
+	}
+
+	t.Run("not retried by default", func(t *testing.T) {
+		db := openSQLite(t)
+		attempts := 0
+		err := Transactional(context.Background(), db, func(ctx context.Context) error {
+			attempts++
+			return busy
+		})
+		assert.ErrorIs(t, err, busy)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		db := openSQLite(t)
+		attempts := 0
+		err := Transactional(context.Background(), db, func(ctx context.Context) error {
+			attempts++
+			return errTest
+		}, WithRetries(3, time.Millisecond))
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestTransactionalTimeout(t *testing.T) {
+	db := openSQLite(t)
+	err := Transactional(context.Background(), db, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "postgres serialization failure", err: &pq.Error{Code: "40001"}, want: true},
+		{name: "postgres deadlock", err: fmt.Errorf("wrapped: %w", &pq.Error{Code: "40P01"}), want: true},
+		{name: "postgres unique violation", err: &pq.Error{Code: "23505"}},
+		{name: "sqlite busy", err: sqlite3.Error{Code: sqlite3.ErrBusy}, want: true},
+		{name: "sqlite locked", err: sqlite3.Error{Code: sqlite3.ErrLocked}, want: true},
+		{name: "sqlite constraint", err: sqlite3.Error{Code: sqlite3.ErrConstraint}},
+		{name: "other", err: errTest},
+		{name: "nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
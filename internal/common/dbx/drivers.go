@@ -0,0 +1,7 @@
+package dbx
+
+import (
+	// database/sql drivers of the supported dialects
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
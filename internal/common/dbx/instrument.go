@@ -0,0 +1,193 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/metrics"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
+	"github.com/jmoiron/sqlx"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultSlowQuery is the duration after which queries are logged
+const DefaultSlowQuery = 200 * time.Millisecond
+
+// Operations of the instrumented DB methods, used as metric labels and
+// span names
+const (
+	opGet       = "get"
+	opSelect    = "select"
+	opExec      = "exec"
+	opNamedExec = "named_exec"
+	opQuery     = "query"
+	opQueryRow  = "query_row"
+)
+
+var (
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of database queries by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation"})
+
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Number of failed database queries by operation, no rows is not a failure.",
+	}, []string{"operation"})
+)
+
+// wrapper is implemented by DBs which wrap another one, Connection and
+// BeginTx use it to find the *sqlx.DB and to wrap the transaction of the
+// context the same way
+type wrapper interface {
+	Unwrap() DB
+	wrapTx(tx *sqlx.Tx) DB
+}
+
+// Instrumented is a DB which records a span, latency and errors of every
+// query, and logs the slow ones. Get the connection with Connection to
+// instrument the queries of transactions started by Transactional as well.
+type Instrumented struct {
+	db        DB
+	logger    *loggerx.Logger
+	dbType    string
+	slowQuery time.Duration
+}
+
+type InstrumentOption func(*Instrumented)
+
+// WithSlowQuery sets the duration after which queries are logged, 0
+// disables the log
+func WithSlowQuery(d time.Duration) InstrumentOption {
+	return func(i *Instrumented) {
+		i.slowQuery = d
+	}
+}
+
+// Instrument wraps db, the driver name tags the spans
+func Instrument(db DB, driverName string, logger *loggerx.Logger, opts ...InstrumentOption) *Instrumented {
+	i := &Instrumented{
+		db:        db,
+		logger:    logger,
+		dbType:    driverName,
+		slowQuery: DefaultSlowQuery,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Unwrap returns the wrapped DB
+func (i *Instrumented) Unwrap() DB {
+	return i.db
+}
+
+func (i *Instrumented) wrapTx(tx *sqlx.Tx) DB {
+	c := *i
+	c.db = tx
+	return &c
+}
+
+func (i *Instrumented) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := i.start(ctx, opGet, query, args)
+	defer func() { done(err) }()
+	return i.db.GetContext(ctx, dest, query, args...)
+}
+
+func (i *Instrumented) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := i.start(ctx, opSelect, query, args)
+	defer func() { done(err) }()
+	return i.db.SelectContext(ctx, dest, query, args...)
+}
+
+func (i *Instrumented) Rebind(query string) string {
+	return i.db.Rebind(query)
+}
+
+func (i *Instrumented) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return i.db.BindNamed(query, arg)
+}
+
+func (i *Instrumented) NamedExecContext(ctx context.Context, query string, arg interface{}) (res sql.Result, err error) {
+	ctx, done := i.start(ctx, opNamedExec, query, []interface{}{arg})
+	defer func() { done(err) }()
+	return i.db.NamedExecContext(ctx, query, arg)
+}
+
+func (i *Instrumented) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	ctx, done := i.start(ctx, opExec, query, args)
+	defer func() { done(err) }()
+	return i.db.ExecContext(ctx, query, args...)
+}
+
+// QueryxContext records the time until the first row is available, not
+// the time to read the rows
+func (i *Instrumented) QueryxContext(ctx context.Context, query string, args ...interface{}) (rows *sqlx.Rows, err error) {
+	ctx, done := i.start(ctx, opQuery, query, args)
+	defer func() { done(err) }()
+	return i.db.QueryxContext(ctx, query, args...)
+}
+
+func (i *Instrumented) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	ctx, done := i.start(ctx, opQueryRow, query, args)
+	row := i.db.QueryRowxContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// start starts the span of a query, the returned func records the result
+func (i *Instrumented) start(ctx context.Context, op, query string, args []interface{}) (context.Context, func(err error)) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "db."+op)
+	ext.SpanKindRPCClient.Set(span)
+	ext.DBType.Set(span, i.dbType)
+	ext.DBStatement.Set(span, query)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		elapsed := time.Since(start)
+		defer span.Finish()
+
+		queryDuration.WithLabelValues(op).Observe(elapsed.Seconds())
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			queryErrors.WithLabelValues(op).Inc()
+			tracing.LogSpanError(span, "query failed", err)
+		}
+
+		if i.slowQuery > 0 && elapsed >= i.slowQuery {
+			i.logger.Warn("slow database query",
+				loggerx.String("operation", op),
+				loggerx.String("query", query),
+				loggerx.Any("args", redactArgs(args)),
+				loggerx.String("duration", elapsed.String()),
+				loggerx.TraceID(tracing.TraceID(ctx)),
+			)
+		}
+	}
+}
+
+// redactArgs replaces query arguments by their types, they may hold
+// personal data or secrets
+func redactArgs(args []interface{}) []string {
+	out := make([]string, len(args))
+	for n, arg := range args {
+		if arg == nil {
+			out[n] = "nil"
+			continue
+		}
+		out[n] = fmt.Sprintf("%T", arg)
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/configx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumented(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	db := openSQLite(t)
+	idb := Instrument(db, "sqlite3", loggerx.NewTestLogger())
+	ctx := context.Background()
+
+	errorsBefore := testutil.ToFloat64(queryErrors.WithLabelValues(opExec))
+	_, err := idb.ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "a")
+	require.NoError(t, err)
+	_, err = idb.ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "a")
+	assert.Error(t, err)
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(queryErrors.WithLabelValues(opExec)))
+
+	getErrorsBefore := testutil.ToFloat64(queryErrors.WithLabelValues(opGet))
+	var id string
+	err = idb.GetContext(ctx, &id, "SELECT id FROM couriers WHERE id = ?", "b")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, getErrorsBefore, testutil.ToFloat64(queryErrors.WithLabelValues(opGet)), "no rows is not an error")
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "db.exec", spans[0].OperationName)
+	assert.Equal(t, "sqlite3", spans[0].Tag("db.type"))
+	assert.Equal(t, "INSERT INTO couriers (id) VALUES (?)", spans[0].Tag("db.statement"))
+	assert.Nil(t, spans[0].Tag("error"))
+	assert.Equal(t, true, spans[1].Tag("error"))
+	assert.Equal(t, "db.get", spans[2].OperationName)
+	assert.Nil(t, spans[2].Tag("error"))
+}
+
+func TestInstrumentedTransactional(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	db := openSQLite(t)
+	idb := Instrument(db, "sqlite3", loggerx.NewTestLogger())
+
+	err := Transactional(context.Background(), idb, func(ctx context.Context) error {
+		conn := Connection(ctx, idb)
+		require.IsType(t, &Instrumented{}, conn)
+		_, err := conn.ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "a")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(t, db))
+	assert.Len(t, tracer.FinishedSpans(), 1)
+
+	tx, _, err := BeginTx(context.Background(), idb)
+	require.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestInstrumentedSlowQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcd.log")
+	logger, err := loggerx.New(configx.ModeProd, "test", loggerx.WithFile(loggerx.FileOptions{Path: path}))
+	require.NoError(t, err)
+
+	db := openSQLite(t)
+	ctx := context.Background()
+	_, err = Instrument(db, "sqlite3", logger).ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "fast")
+	require.NoError(t, err)
+	_, err = Instrument(db, "sqlite3", logger, WithSlowQuery(1)).ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "secret")
+	require.NoError(t, err)
+	_ = logger.Sync()
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, countLines(out))
+	assert.Contains(t, string(out), "slow database query")
+	assert.Contains(t, string(out), `"args":["string"]`)
+	assert.NotContains(t, string(out), "secret")
+}
+
+func countLines(b []byte) int {
+	n := 0
+	for _, c := range b {
+		if c == '\n' {
+			n++
+		}
+	}
+	return n
+}
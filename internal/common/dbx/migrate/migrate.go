@@ -0,0 +1,437 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Directions of a Step
+const (
+	Up   = "up"
+	Down = "down"
+)
+
+const (
+	// DefaultTable records the applied migrations
+	DefaultTable = "schema_migrations"
+
+	// noTransaction is a line of scripts which can not run in a transaction,
+	// e.g. CREATE INDEX CONCURRENTLY on Postgres
+	noTransaction = "-- migrate:no-transaction"
+
+	lockPollInterval = 500 * time.Millisecond
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrUnknownVersion   = errors.New("applied migration is unknown")
+	ErrIrreversible     = errors.New("migration can not be reverted")
+	ErrLocked           = errors.New("migrations are locked")
+)
+
+// Step applies or reverts a migration
+type Step struct {
+	Direction string
+	Migration Migration
+}
+
+// SQL returns the script run by the step
+func (s Step) SQL() string {
+	if s.Direction == Down {
+		return s.Migration.Down
+	}
+	return s.Migration.Up
+}
+
+// Status is the state of a migration in the database
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is true if the applied checksum differs from the file
+	Modified bool
+}
+
+// Migrator applies migrations and records them in a table. Concurrent
+// migrators are serialized with an advisory lock on Postgres and a lock
+// table on SQLite.
+type Migrator struct {
+	db          *sqlx.DB
+	dialect     string
+	migrations  []Migration
+	table       string
+	lockTimeout time.Duration
+	owner       string
+}
+
+type Option func(*Migrator)
+
+// WithTable sets the table recording the applied migrations, the lock
+// table is named after it with a _lock suffix
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockTimeout limits how long Up and Down wait for a concurrent
+// migrator, they wait until their context is done by default
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = d
+	}
+}
+
+// New returns a migrator of the migrations loaded by Load
+// Error ErrUnknownDialect
+func New(db *sqlx.DB, migrations []Migration, opts ...Option) (*Migrator, error) {
+	dialect, err := DialectOf(db.DriverName())
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	m := &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+		table:      DefaultTable,
+		owner:      fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Dialect returns the dialect of the database
+func (m *Migrator) Dialect() string {
+	return m.dialect
+}
+
+// Status returns the state of every known migration and of applied
+// migrations which are unknown, ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		s := Status{Migration: mig}
+		if a, ok := applied[mig.Version]; ok {
+			s.Applied, s.AppliedAt = true, a.AppliedAt
+			s.Modified = a.Checksum != mig.Checksum()
+		}
+		out = append(out, s)
+	}
+	for version, a := range applied {
+		if !known[version] {
+			out = append(out, Status{
+				Migration: Migration{Version: version, Name: a.Name},
+				Applied:   true,
+				AppliedAt: a.AppliedAt,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Migration.Version < out[j].Migration.Version
+	})
+	return out, nil
+}
+
+// PlanUp returns the steps Up would run, the database is not modified
+func (m *Migrator) PlanUp(ctx context.Context, to int64) ([]Step, error) {
+	return m.planUp(ctx, m.db, to)
+}
+
+// PlanDown returns the steps Down would run, the database is not modified
+func (m *Migrator) PlanDown(ctx context.Context, n int) ([]Step, error) {
+	return m.planDown(ctx, m.db, n)
+}
+
+// Up applies the pending migrations up to version to, all of them if to is
+// zero, each in its own transaction. It returns the applied steps, also
+// when a step failed.
+// Error ErrChecksumMismatch, ErrUnknownVersion, ErrLocked
+func (m *Migrator) Up(ctx context.Context, to int64) ([]Step, error) {
+	return m.run(ctx, func(conn *sqlx.Conn) ([]Step, error) {
+		return m.planUp(ctx, conn, to)
+	})
+}
+
+// Down reverts the last n applied migrations, newest first
+// Error ErrChecksumMismatch, ErrUnknownVersion, ErrIrreversible, ErrLocked
+func (m *Migrator) Down(ctx context.Context, n int) ([]Step, error) {
+	return m.run(ctx, func(conn *sqlx.Conn) ([]Step, error) {
+		return m.planDown(ctx, conn, n)
+	})
+}
+
+// Unlock releases a lock left by a migrator which did not exit cleanly,
+// Postgres releases locks of closed sessions by itself
+func (m *Migrator) Unlock(ctx context.Context) error {
+	if m.dialect == Postgres {
+		return nil
+	}
+	if ok, err := m.tableExists(ctx, m.db, m.lockTable()); err != nil || !ok {
+		return err
+	}
+	_, err := m.db.ExecContext(ctx, "DELETE FROM "+m.lockTable())
+	return err
+}
+
+func (m *Migrator) run(ctx context.Context, plan func(conn *sqlx.Conn) ([]Step, error)) ([]Step, error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// the table is created under the lock, concurrent CREATE TABLE IF NOT
+	// EXISTS statements can fail on Postgres
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	steps, err := plan(conn)
+	if err != nil {
+		return nil, err
+	}
+	for i, step := range steps {
+		if err := m.apply(ctx, conn, step); err != nil {
+			return steps[:i], fmt.Errorf("failed to migrate %s %s: %w", step.Direction, step.Migration, err)
+		}
+	}
+	return steps, nil
+}
+
+type applied struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// applied returns the applied migrations, none if the table does not exist
+func (m *Migrator) applied(ctx context.Context, q sqlx.QueryerContext) (map[int64]applied, error) {
+	if ok, err := m.tableExists(ctx, q, m.table); err != nil || !ok {
+		return map[int64]applied{}, err
+	}
+	var rows []applied
+	err := sqlx.SelectContext(ctx, q, &rows, "SELECT version, name, checksum, applied_at FROM "+m.table+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	out := make(map[int64]applied, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// verify checks applied migrations are known and were not modified
+func (m *Migrator) verify(applied map[int64]applied) error {
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	for version, a := range applied {
+		mig, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %04d_%s", ErrUnknownVersion, version, a.Name)
+		}
+		if a.Checksum != mig.Checksum() {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) planUp(ctx context.Context, q sqlx.QueryerContext, to int64) ([]Step, error) {
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	var steps []Step
+	for _, mig := range m.migrations {
+		if to > 0 && mig.Version > to {
+			break
+		}
+		if _, ok := applied[mig.Version]; !ok {
+			steps = append(steps, Step{Direction: Up, Migration: mig})
+		}
+	}
+	return steps, nil
+}
+
+func (m *Migrator) planDown(ctx context.Context, q sqlx.QueryerContext, n int) ([]Step, error) {
+	applied, err := m.applied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	var steps []Step
+	for i := len(m.migrations) - 1; i >= 0 && len(steps) < n; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return nil, fmt.Errorf("%w: %s", ErrIrreversible, mig)
+		}
+		steps = append(steps, Step{Direction: Down, Migration: mig})
+	}
+	return steps, nil
+}
+
+// apply runs the script of step and records it in the same transaction,
+// unless the script opts out of transactions
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, step Step) error {
+	record := func(e sqlx.ExecerContext) error {
+		var err error
+		if step.Direction == Up {
+			_, err = e.ExecContext(ctx, m.rebind("INSERT INTO "+m.table+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+				step.Migration.Version, step.Migration.Name, step.Migration.Checksum(), time.Now().UTC())
+		} else {
+			_, err = e.ExecContext(ctx, m.rebind("DELETE FROM "+m.table+" WHERE version = ?"), step.Migration.Version)
+		}
+		return err
+	}
+
+	script := step.SQL()
+	if strings.Contains(script, noTransaction) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) tableExists(ctx context.Context, q sqlx.QueryerContext, table string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if m.dialect == Postgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+	var n int
+	if err := sqlx.GetContext(ctx, q, &n, m.rebind(query), table); err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, e sqlx.ExecerContext) error {
+	_, err := e.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", m.table, err)
+	}
+	return nil
+}
+
+// ensureLockTable creates the table holding the lock on databases without
+// advisory locks, SQLite serializes the statement itself
+func (m *Migrator) ensureLockTable(ctx context.Context, e sqlx.ExecerContext) error {
+	_, err := e.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.lockTable()+` (
+	id INTEGER PRIMARY KEY,
+	owner TEXT NOT NULL,
+	locked_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", m.lockTable(), err)
+	}
+	return nil
+}
+
+// lock waits until the migrations are locked by conn, ctx is done or the
+// lock timeout expired
+func (m *Migrator) lock(ctx context.Context, conn *sqlx.Conn) (func(), error) {
+	if m.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.lockTimeout)
+		defer cancel()
+	}
+	if m.dialect == Postgres {
+		key := int64(crc32.ChecksumIEEE([]byte(m.table)))
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLocked, err)
+		}
+		return func() {
+			_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		}, nil
+	}
+
+	if err := m.ensureLockTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := conn.ExecContext(ctx, m.rebind("INSERT INTO "+m.lockTable()+" (id, owner, locked_at) VALUES (1, ?, ?)"), m.owner, time.Now().UTC())
+		if err == nil {
+			return func() {
+				_, _ = conn.ExecContext(context.Background(), "DELETE FROM "+m.lockTable()+" WHERE id = 1")
+			}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to lock migrations: %w", ctx.Err())
+		}
+		var owner string
+		if qerr := conn.GetContext(ctx, &owner, "SELECT owner FROM "+m.lockTable()+" WHERE id = 1"); qerr != nil {
+			// the insert did not fail because of the lock
+			return nil, fmt.Errorf("failed to lock migrations: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w by %s, run migrate unlock if it is not running", ErrLocked, owner)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Migrator) lockTable() string {
+	return m.table + "_lock"
+}
+
+func (m *Migrator) rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(m.db.DriverName()), query)
+}
@@ -0,0 +1,334 @@
+package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_couriers.up.sql":        {Data: []byte("CREATE TABLE couriers (id TEXT PRIMARY KEY);")},
+		"0001_create_couriers.down.sql":      {Data: []byte("DROP TABLE couriers;")},
+		"0002_add_name.up.sql":               {Data: []byte("ALTER TABLE couriers ADD COLUMN name TEXT;")},
+		"0002_add_name.down.sql":             {Data: []byte("ALTER TABLE couriers DROP COLUMN name;")},
+		"0003_add_index.up.sql":              {Data: []byte("CREATE INDEX couriers_name_idx ON couriers (name);")},
+		"0003_add_index.up.postgres.sql":     {Data: []byte("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY couriers_name_idx ON couriers (name);")},
+		"0003_add_index.down.sql":            {Data: []byte("DROP INDEX couriers_name_idx;")},
+		"0004_add_phone.up.sqlite.sql":       {Data: []byte("ALTER TABLE couriers ADD COLUMN phone TEXT;")},
+		"0004_add_phone.up.postgres.sql":     {Data: []byte("ALTER TABLE couriers ADD COLUMN phone VARCHAR(32);")},
+		"0004_add_phone.down.postgres.sql":   {Data: []byte("ALTER TABLE couriers DROP COLUMN phone;")},
+		"0004_add_phone.down.sqlite.sql":     {Data: []byte("ALTER TABLE couriers DROP COLUMN phone;")},
+		"0005_backfill.up.sql":               {Data: []byte("UPDATE couriers SET name = id WHERE name IS NULL;")},
+		"0006_split_name.up.sql":             {Data: []byte("ALTER TABLE couriers ADD COLUMN last_name TEXT;")},
+		"0006_split_name.down.sql":           {Data: []byte("ALTER TABLE couriers DROP COLUMN last_name;")},
+		"0007_unsupported.up.postgres.sql":   {Data: []byte("CREATE EXTENSION postgis;")},
+		"0007_unsupported.down.postgres.sql": {Data: []byte("DROP EXTENSION postgis;")},
+		"0007_unsupported.up.sqlite.sql":     {Data: []byte("SELECT 1;")},
+		"0007_unsupported.down.sqlite.sql":   {Data: []byte("SELECT 1;")},
+	}
+}
+
+func openSQLite(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newMigrator(t *testing.T, db *sqlx.DB, fsys fstest.MapFS, opts ...Option) *Migrator {
+	t.Helper()
+	migs, err := Load(fsys, SQLite)
+	require.NoError(t, err)
+	m, err := New(db, migs, opts...)
+	require.NoError(t, err)
+	return m
+}
+
+func versions(steps []Step) []int64 {
+	out := make([]int64, 0, len(steps))
+	for _, s := range steps {
+		out = append(out, s.Migration.Version)
+	}
+	return out
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		dialect  string
+		versions []int64
+		up       map[int64]string
+		err      error
+	}{
+		{
+			name:     "sqlite",
+			fsys:     testFS(),
+			dialect:  SQLite,
+			versions: []int64{1, 2, 3, 4, 5, 6, 7},
+			up: map[int64]string{
+				3: "CREATE INDEX couriers_name_idx ON couriers (name);",
+				4: "ALTER TABLE couriers ADD COLUMN phone TEXT;",
+			},
+		},
+		{
+			name:     "postgres overrides generic files",
+			fsys:     testFS(),
+			dialect:  Postgres,
+			versions: []int64{1, 2, 3, 4, 5, 6, 7},
+			up: map[int64]string{
+				3: "-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY couriers_name_idx ON couriers (name);",
+				4: "ALTER TABLE couriers ADD COLUMN phone VARCHAR(32);",
+				7: "CREATE EXTENSION postgis;",
+			},
+		},
+		{
+			name:    "invalid name",
+			fsys:    fstest.MapFS{"create_couriers.sql": {}},
+			dialect: SQLite,
+			err:     ErrInvalidFileName,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_a.up.sql": {},
+				"0001_b.up.sql": {},
+			},
+			dialect: SQLite,
+			err:     ErrDuplicate,
+		},
+		{
+			name:    "no up file",
+			fsys:    fstest.MapFS{"0001_a.down.sql": {}},
+			dialect: SQLite,
+			err:     ErrNoUp,
+		},
+		{
+			name:    "up file of another dialect only",
+			fsys:    fstest.MapFS{"0001_a.up.postgres.sql": {}, "0001_a.down.sql": {}},
+			dialect: SQLite,
+			err:     ErrNoUp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migs, err := Load(tt.fsys, tt.dialect)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			var got []int64
+			for _, m := range migs {
+				got = append(got, m.Version)
+				if up, ok := tt.up[m.Version]; ok {
+					assert.Equal(t, up, m.Up, m.String())
+				}
+			}
+			assert.Equal(t, tt.versions, got)
+		})
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	tests := []struct {
+		driver  string
+		dialect string
+		err     error
+	}{
+		{driver: "postgres", dialect: Postgres},
+		{driver: "pgx", dialect: Postgres},
+		{driver: "sqlite3", dialect: SQLite},
+		{driver: "mysql", err: ErrUnknownDialect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			dialect, err := DialectOf(tt.driver)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.dialect, dialect)
+		})
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	fsys := testFS()
+	delete(fsys, "0005_backfill.up.sql")
+	m := newMigrator(t, db, fsys)
+
+	plan, err := m.PlanUp(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, versions(plan))
+	exists, err := m.tableExists(ctx, db, DefaultTable)
+	require.NoError(t, err)
+	assert.False(t, exists, "planning must not modify the database")
+
+	steps, err := m.Up(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, versions(steps))
+
+	steps, err = m.Up(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4, 6, 7}, versions(steps))
+	_, err = db.Exec("INSERT INTO couriers (id, name, phone, last_name) VALUES ('c1', 'a', 'b', 'c')")
+	require.NoError(t, err)
+
+	steps, err = m.Up(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 6)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, s.Migration.String())
+		assert.False(t, s.Modified, s.Migration.String())
+		assert.WithinDuration(t, time.Now(), s.AppliedAt, time.Minute)
+	}
+
+	plan, err = m.PlanDown(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7, 6}, versions(plan))
+	assert.Equal(t, Down, plan[0].Direction)
+	assert.Equal(t, "SELECT 1;", plan[0].SQL())
+
+	steps, err = m.Down(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7, 6, 4}, versions(steps))
+	_, err = db.Exec("SELECT last_name FROM couriers")
+	assert.Error(t, err)
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	var applied []int64
+	for _, s := range statuses {
+		if s.Applied {
+			applied = append(applied, s.Migration.Version)
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 3}, applied)
+}
+
+func TestFailedStep(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m := newMigrator(t, db, fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"0002_b.up.sql": {Data: []byte("CREATE TABLE b (id INTEGER); CREATE TABLE a (id INTEGER);")},
+	})
+
+	steps, err := m.Up(ctx, 0)
+	assert.Error(t, err)
+	assert.Equal(t, []int64{1}, versions(steps))
+
+	// the failed script was rolled back with its record
+	_, err = db.Exec("SELECT id FROM b")
+	assert.Error(t, err)
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("modified", func(t *testing.T) {
+		db := openSQLite(t)
+		fsys := testFS()
+		_, err := newMigrator(t, db, fsys).Up(ctx, 2)
+		require.NoError(t, err)
+
+		fsys["0002_add_name.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE couriers ADD COLUMN full_name TEXT;")}
+		m := newMigrator(t, db, fsys)
+		_, err = m.Up(ctx, 0)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		_, err = m.PlanDown(ctx, 1)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.False(t, statuses[0].Modified)
+		assert.True(t, statuses[1].Modified)
+	})
+
+	t.Run("modified down", func(t *testing.T) {
+		db := openSQLite(t)
+		fsys := testFS()
+		_, err := newMigrator(t, db, fsys).Up(ctx, 2)
+		require.NoError(t, err)
+
+		fsys["0002_add_name.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE couriers;")}
+		m := newMigrator(t, db, fsys)
+		_, err = m.Down(ctx, 1)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		db := openSQLite(t)
+		_, err := newMigrator(t, db, testFS()).Up(ctx, 2)
+		require.NoError(t, err)
+
+		fsys := testFS()
+		m := newMigrator(t, db, fstest.MapFS{
+			"0001_create_couriers.up.sql":   fsys["0001_create_couriers.up.sql"],
+			"0001_create_couriers.down.sql": fsys["0001_create_couriers.down.sql"],
+		})
+		_, err = m.Up(ctx, 0)
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+		assert.Equal(t, "add_name", statuses[1].Migration.Name)
+		assert.True(t, statuses[1].Applied)
+	})
+
+	t.Run("irreversible", func(t *testing.T) {
+		db := openSQLite(t)
+		m := newMigrator(t, db, testFS())
+		_, err := m.Up(ctx, 5)
+		require.NoError(t, err)
+
+		_, err = m.Down(ctx, 2)
+		assert.ErrorIs(t, err, ErrIrreversible)
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, statuses[4].Applied, "no step runs when one of them is irreversible")
+	})
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m := newMigrator(t, db, testFS(), WithLockTimeout(50*time.Millisecond))
+
+	conn, err := db.Connx(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = m.lock(ctx, conn)
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx, 0)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Contains(t, err.Error(), m.owner)
+
+	require.NoError(t, m.Unlock(ctx))
+	steps, err := m.Up(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, steps, 7)
+
+	// the lock is released after a run
+	_, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+}
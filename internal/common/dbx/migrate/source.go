@@ -0,0 +1,142 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Dialects of the supported databases
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+var (
+	ErrUnknownDialect  = errors.New("unknown database dialect")
+	ErrInvalidFileName = errors.New("invalid migration file name")
+	ErrDuplicate       = errors.New("duplicate migration")
+	ErrNoUp            = errors.New("migration has no up file")
+)
+
+// fileRegex matches e.g. 0001_create_couriers.up.sql, or
+// 0002_add_geometry.up.postgres.sql which replaces the generic file of the
+// version on Postgres
+var fileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)(?:\.(postgres|sqlite))?\.sql$`)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down reverts Up, the migration is irreversible if it is empty
+	Down string
+}
+
+// Checksum identifies the up and down scripts, a changed checksum of an
+// applied migration means one of its files was edited after it was
+// applied, and reverting it may no longer match what was applied
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	h.Write([]byte(m.Up))
+	h.Write([]byte{0})
+	h.Write([]byte(m.Down))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// DialectOf returns the dialect of a database/sql driver name
+func DialectOf(driverName string) (string, error) {
+	switch driverName {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return Postgres, nil
+	case "sqlite3", "sqlite":
+		return SQLite, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownDialect, driverName)
+	}
+}
+
+// Load reads the migrations of dialect from the .sql files at the root of
+// fsys, ordered by version. Files of other dialects are ignored and files
+// of dialect replace the generic ones of the same version.
+func Load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	type script struct {
+		sql      string
+		specific bool
+	}
+	type files struct {
+		name     string
+		up, down *script
+	}
+	byVersion := make(map[int64]*files)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := fileRegex.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, e.Name())
+		}
+		if match[4] != "" && match[4] != dialect {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, e.Name())
+		}
+		content, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+		}
+
+		f, ok := byVersion[version]
+		if !ok {
+			f = &files{name: match[2]}
+			byVersion[version] = f
+		}
+		if f.name != match[2] {
+			return nil, fmt.Errorf("%w: version %d is named %s and %s", ErrDuplicate, version, f.name, match[2])
+		}
+		s := &script{sql: string(content), specific: match[4] != ""}
+		target := &f.up
+		if match[3] == "down" {
+			target = &f.down
+		}
+		switch {
+		case *target == nil || (s.specific && !(*target).specific):
+			*target = s
+		case s.specific == (*target).specific:
+			return nil, fmt.Errorf("%w: %s", ErrDuplicate, e.Name())
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for version, f := range byVersion {
+		if f.up == nil {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrNoUp, version, f.name)
+		}
+		m := Migration{Version: version, Name: f.name, Up: f.up.sql}
+		if f.down != nil {
+			m.Down = f.down.sql
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Version < out[j].Version
+	})
+	return out, nil
+}
@@ -0,0 +1,312 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/metrics"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultMaxLag is the replication lag after which replicas are not read
+const DefaultMaxLag = 10 * time.Second
+
+// postgresLagQuery returns the replay lag in seconds. It is 0 when the
+// replica streams from the primary and replayed everything it received, so
+// an idle primary is not mistaken for lag. A replica whose WAL receiver is
+// not streaming may be missing any amount of WAL, its lag is the age of
+// the last replayed transaction, NULL if it did not replay any.
+const postgresLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+		AND EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming') THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END`
+
+var ErrReplicaUnhealthy = errors.New("replica is unhealthy")
+
+var (
+	replicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "replica_lag_seconds",
+		Help:      "Replication lag of read replicas by replica.",
+	}, []string{"replica"})
+
+	replicaHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "replica_healthy",
+		Help:      "Whether read replicas receive reads, by replica.",
+	}, []string{"replica"})
+)
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites returns a context whose reads go to the primary, for
+// reads which must see writes made just before
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+func readYourWrites(ctx context.Context) bool {
+	v, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return v
+}
+
+// ReplicaStatus is the result of the last check of a replica
+type ReplicaStatus struct {
+	Name      string
+	Healthy   bool
+	Lag       time.Duration
+	Err       error
+	CheckedAt time.Time
+}
+
+type replica struct {
+	db DB
+
+	mu     sync.RWMutex
+	status ReplicaStatus
+}
+
+func (r *replica) healthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status.Healthy
+}
+
+// Cluster is a DB of a primary and read replicas. GetContext and
+// SelectContext outside transactions go to the healthy replicas in turn,
+// or to the primary when none is healthy or the context requests read your
+// writes, and inside transactions started by Transactional to the
+// transaction. Other queries go to the primary, or to the transaction of
+// the context.
+type Cluster struct {
+	primary  DB
+	replicas []*replica
+	next     uint32
+	maxLag   time.Duration
+	lagQuery string
+}
+
+type ClusterOption func(*Cluster)
+
+// WithMaxLag sets the replication lag after which replicas are not read,
+// 0 ignores the lag
+func WithMaxLag(d time.Duration) ClusterOption {
+	return func(c *Cluster) {
+		c.maxLag = d
+	}
+}
+
+// WithLagQuery sets the query returning the lag of a replica in seconds,
+// the lag is not checked on other databases than Postgres by default
+func WithLagQuery(query string) ClusterOption {
+	return func(c *Cluster) {
+		c.lagQuery = query
+	}
+}
+
+// NewCluster returns a cluster of primary and replicas of driverName,
+// replicas are considered healthy until they are checked
+func NewCluster(primary DB, driverName string, replicas []DB, opts ...ClusterOption) *Cluster {
+	c := &Cluster{
+		primary:  primary,
+		replicas: make([]*replica, 0, len(replicas)),
+		maxLag:   DefaultMaxLag,
+		lagQuery: "SELECT 0",
+	}
+	switch driverName {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		c.lagQuery = postgresLagQuery
+	}
+	for n, db := range replicas {
+		name := fmt.Sprintf("replica_%d", n)
+		c.replicas = append(c.replicas, &replica{
+			db:     db,
+			status: ReplicaStatus{Name: name, Healthy: true},
+		})
+		replicaHealthy.WithLabelValues(name).Set(1)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Primary returns the primary DB
+func (c *Cluster) Primary() DB {
+	return c.primary
+}
+
+// Unwrap returns the primary DB
+func (c *Cluster) Unwrap() DB {
+	return c.primary
+}
+
+func (c *Cluster) wrapTx(tx *sqlx.Tx) DB {
+	if w, ok := c.primary.(wrapper); ok {
+		return w.wrapTx(tx)
+	}
+	return tx
+}
+
+// reader returns the DB of a read, the transaction of ctx if there is one
+func (c *Cluster) reader(ctx context.Context) DB {
+	if tx, ok := txFromContext(ctx); ok {
+		return c.wrapTx(tx)
+	}
+	if readYourWrites(ctx) || len(c.replicas) == 0 {
+		return c.primary
+	}
+	start := int(atomic.AddUint32(&c.next, 1))
+	for k := range c.replicas {
+		r := c.replicas[(start+k)%len(c.replicas)]
+		if r.healthy() {
+			return r.db
+		}
+	}
+	return c.primary
+}
+
+// writer returns the DB of a write, the transaction of ctx if there is one
+func (c *Cluster) writer(ctx context.Context) DB {
+	if tx, ok := txFromContext(ctx); ok {
+		return c.wrapTx(tx)
+	}
+	return c.primary
+}
+
+func (c *Cluster) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.reader(ctx).GetContext(ctx, dest, query, args...)
+}
+
+func (c *Cluster) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.reader(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+func (c *Cluster) Rebind(query string) string {
+	return c.primary.Rebind(query)
+}
+
+func (c *Cluster) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return c.primary.BindNamed(query, arg)
+}
+
+func (c *Cluster) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	return c.writer(ctx).NamedExecContext(ctx, query, arg)
+}
+
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.writer(ctx).ExecContext(ctx, query, args...)
+}
+
+// QueryxContext queries the primary or the transaction of ctx, the query
+// may write with RETURNING
+func (c *Cluster) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return c.writer(ctx).QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext queries the primary or the transaction of ctx, the query
+// may write with RETURNING
+func (c *Cluster) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return c.writer(ctx).QueryRowxContext(ctx, query, args...)
+}
+
+// CheckReplicas measures the lag of every replica, replicas which fail or
+// lag more than the max lag stop receiving reads until a later check
+// succeeds
+func (c *Cluster) CheckReplicas(ctx context.Context) []ReplicaStatus {
+	out := make([]ReplicaStatus, len(c.replicas))
+	var wg sync.WaitGroup
+	for n, r := range c.replicas {
+		wg.Add(1)
+		go func(n int, r *replica) {
+			defer wg.Done()
+			out[n] = c.checkReplica(ctx, r)
+		}(n, r)
+	}
+	wg.Wait()
+	return out
+}
+
+func (c *Cluster) checkReplica(ctx context.Context, r *replica) ReplicaStatus {
+	r.mu.RLock()
+	status := ReplicaStatus{Name: r.status.Name, CheckedAt: time.Now()}
+	r.mu.RUnlock()
+
+	var lag sql.NullFloat64
+	if err := r.db.GetContext(ctx, &lag, c.lagQuery); err != nil {
+		status.Err = fmt.Errorf("failed to check lag: %w", err)
+	} else if !lag.Valid {
+		status.Err = errors.New("unknown lag, the replica is not streaming and replayed no transaction")
+	} else {
+		seconds := lag.Float64
+		status.Lag = time.Duration(seconds * float64(time.Second))
+		if c.maxLag > 0 && status.Lag > c.maxLag {
+			status.Err = fmt.Errorf("lag %s exceeds %s", status.Lag, c.maxLag)
+		}
+		replicaLag.WithLabelValues(status.Name).Set(seconds)
+	}
+	status.Healthy = status.Err == nil
+	if status.Healthy {
+		replicaHealthy.WithLabelValues(status.Name).Set(1)
+	} else {
+		replicaHealthy.WithLabelValues(status.Name).Set(0)
+	}
+
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+	return status
+}
+
+// Replicas returns the result of the last check of every replica
+func (c *Cluster) Replicas() []ReplicaStatus {
+	out := make([]ReplicaStatus, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		r.mu.RLock()
+		out = append(out, r.status)
+		r.mu.RUnlock()
+	}
+	return out
+}
+
+// Watch checks the replicas every interval until ctx is done
+func (c *Cluster) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.CheckReplicas(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reports the replicas found unhealthy by the last check, reads fall
+// back to the primary so it is suitable as an optional health check
+// Error ErrReplicaUnhealthy
+func (c *Cluster) Check(_ context.Context) error {
+	var failed []string
+	for _, s := range c.Replicas() {
+		if !s.Healthy {
+			failed = append(failed, fmt.Sprintf("%s: %v", s.Name, s.Err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrReplicaUnhealthy, strings.Join(failed, "; "))
+}
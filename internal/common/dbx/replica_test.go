@@ -0,0 +1,162 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCluster returns a cluster of SQLite databases whose couriers
+// table holds the name of the database, and whose lag table holds the lag
+// reported by the lag query
+func newTestCluster(t *testing.T, opts ...ClusterOption) (*Cluster, map[string]*sqlx.DB) {
+	t.Helper()
+	dbs := map[string]*sqlx.DB{}
+	for _, name := range []string{"primary", "replica_0", "replica_1"} {
+		db := openSQLite(t)
+		_, err := db.Exec("CREATE TABLE lag (seconds REAL NOT NULL); INSERT INTO lag VALUES (0);")
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO couriers (id) VALUES (?)", name)
+		require.NoError(t, err)
+		dbs[name] = db
+	}
+	opts = append([]ClusterOption{WithLagQuery("SELECT seconds FROM lag"), WithMaxLag(time.Second)}, opts...)
+	return NewCluster(dbs["primary"], "sqlite3", []DB{dbs["replica_0"], dbs["replica_1"]}, opts...), dbs
+}
+
+func readFrom(t *testing.T, ctx context.Context, db DB) string {
+	t.Helper()
+	var name string
+	require.NoError(t, db.GetContext(ctx, &name, "SELECT id FROM couriers"))
+	return name
+}
+
+func TestClusterRouting(t *testing.T) {
+	c, dbs := newTestCluster(t)
+	ctx := context.Background()
+
+	seen := map[string]int{}
+	for n := 0; n < 4; n++ {
+		seen[readFrom(t, ctx, c)]++
+	}
+	assert.Equal(t, map[string]int{"replica_0": 2, "replica_1": 2}, seen)
+
+	var names []string
+	require.NoError(t, c.SelectContext(ctx, &names, "SELECT id FROM couriers"))
+	assert.NotEqual(t, []string{"primary"}, names)
+
+	assert.Equal(t, "primary", readFrom(t, WithReadYourWrites(ctx), c))
+
+	_, err := c.ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "written")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, dbs["primary"].Get(&count, "SELECT COUNT(*) FROM couriers"))
+	assert.Equal(t, 2, count)
+
+	err = Transactional(ctx, c, func(ctx context.Context) error {
+		if err := insert(ctx, c, "uncommitted"); err != nil {
+			return err
+		}
+		var names []string
+		require.NoError(t, c.SelectContext(ctx, &names, "SELECT id FROM couriers ORDER BY id"))
+		assert.Equal(t, []string{"primary", "uncommitted", "written"}, names)
+		assert.IsType(t, &sqlx.Tx{}, Connection(ctx, c))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestClusterReplicaHealth(t *testing.T) {
+	c, dbs := newTestCluster(t)
+	ctx := context.Background()
+
+	_, err := dbs["replica_0"].Exec("UPDATE lag SET seconds = 5")
+	require.NoError(t, err)
+	statuses := c.CheckReplicas(ctx)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].Healthy)
+	assert.Equal(t, 5*time.Second, statuses[0].Lag)
+	assert.True(t, statuses[1].Healthy)
+	assert.ErrorIs(t, c.Check(ctx), ErrReplicaUnhealthy)
+	for n := 0; n < 3; n++ {
+		assert.Equal(t, "replica_1", readFrom(t, ctx, c))
+	}
+
+	require.NoError(t, dbs["replica_1"].Close())
+	statuses = c.CheckReplicas(ctx)
+	assert.False(t, statuses[1].Healthy)
+	assert.Error(t, statuses[1].Err)
+	assert.Equal(t, "primary", readFrom(t, ctx, c), "reads fall back to the primary")
+
+	_, err = dbs["replica_0"].Exec("UPDATE lag SET seconds = 0.5")
+	require.NoError(t, err)
+	c.CheckReplicas(ctx)
+	assert.Equal(t, "replica_0", readFrom(t, ctx, c))
+	assert.Equal(t, []bool{true, false}, []bool{c.Replicas()[0].Healthy, c.Replicas()[1].Healthy})
+}
+
+func TestClusterUnknownLag(t *testing.T) {
+	c, _ := newTestCluster(t, WithLagQuery("SELECT NULL"))
+	ctx := context.Background()
+
+	for _, status := range c.CheckReplicas(ctx) {
+		assert.False(t, status.Healthy)
+		assert.Error(t, status.Err)
+	}
+	assert.Equal(t, "primary", readFrom(t, ctx, c))
+}
+
+func TestClusterWithoutReplicas(t *testing.T) {
+	db := openSQLite(t)
+	c := NewCluster(db, "sqlite3", nil)
+	ctx := context.Background()
+
+	_, err := c.ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", readFrom(t, ctx, c))
+	assert.Empty(t, c.CheckReplicas(ctx))
+	assert.NoError(t, c.Check(ctx))
+}
+
+func TestClusterInstrumented(t *testing.T) {
+	db := openSQLite(t)
+	primary := Instrument(db, "sqlite3", nil, WithSlowQuery(0))
+	c := NewCluster(primary, "sqlite3", nil)
+
+	err := Transactional(context.Background(), c, func(ctx context.Context) error {
+		assert.IsType(t, &Instrumented{}, Connection(ctx, c))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestClusterWritesInTransaction(t *testing.T) {
+	c, dbs := newTestCluster(t)
+	ctx := context.Background()
+
+	errRollback := errors.New("rollback")
+	err := Transactional(ctx, c, func(ctx context.Context) error {
+		_, err := c.ExecContext(ctx, "INSERT INTO couriers (id) VALUES (?)", "exec")
+		require.NoError(t, err)
+		_, err = c.NamedExecContext(ctx, "INSERT INTO couriers (id) VALUES (:id)", map[string]interface{}{"id": "named"})
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, c.QueryRowxContext(ctx, "SELECT COUNT(*) FROM couriers").Scan(&n))
+		assert.Equal(t, 3, n, "the writes are seen by the transaction")
+		rows, err := c.QueryxContext(ctx, "SELECT id FROM couriers WHERE id = ?", "named")
+		require.NoError(t, err)
+		assert.True(t, rows.Next())
+		require.NoError(t, rows.Close())
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	var count int
+	require.NoError(t, dbs["primary"].Get(&count, "SELECT COUNT(*) FROM couriers"))
+	assert.Equal(t, 1, count, "the writes are rolled back with the transaction")
+}
@@ -0,0 +1,99 @@
+package dbx
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	defaultRetries = 0
+	defaultBackoff = 10 * time.Millisecond
+	maxBackoff     = time.Second
+)
+
+type txOptions struct {
+	isolation sql.IsolationLevel
+	readOnly  bool
+	timeout   time.Duration
+	retries   int
+	backoff   time.Duration
+}
+
+func defaultTxOptions() txOptions {
+	return txOptions{retries: defaultRetries, backoff: defaultBackoff}
+}
+
+// TxOption configures Transactional, only WithTimeout applies to nested
+// transactions since savepoints share the options of the outer one
+type TxOption func(*txOptions)
+
+// WithIsolation sets the isolation level, the driver default is used if it
+// is not set
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.isolation = level
+	}
+}
+
+// ReadOnly starts a read-only transaction
+func ReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.readOnly = true
+	}
+}
+
+// WithTimeout cancels the transaction and its retries after d
+func WithTimeout(d time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.timeout = d
+	}
+}
+
+// WithRetries sets how many times a transaction failing with a retryable
+// error is run again, waiting backoff before the first retry and twice as
+// long before each next one, up to a second. A zero backoff waits 10ms.
+// Transactions are not retried by default: the func runs once per attempt,
+// so only callers whose func has no side effects outside the transaction
+// should opt in.
+func WithRetries(n int, backoff time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.retries = n
+		if backoff > 0 {
+			o.backoff = backoff
+		}
+	}
+}
+
+// IsRetryable reports whether err is a serialization failure or a deadlock
+// after which the transaction can succeed when run again
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", // serialization_failure
+			"40P01": // deadlock_detected
+			return true
+		}
+		return false
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// jitter returns a random duration between d/2 and d, so concurrent
+// retries spread out
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
@@ -1,15 +1,127 @@
 package errorx
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+)
+
+// Codes are stable identifiers of failures which clients can rely on
+const (
+	CodeBadRequest           = "BAD_REQUEST"
+	CodeValidation           = "VALIDATION"
+	CodeCalculate            = "CALCULATE"
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeForbidden            = "FORBIDDEN"
+	CodeNotFound             = "NOT_FOUND"
+	CodeMethodNotAllowed     = "METHOD_NOT_ALLOWED"
+	CodeConflict             = "CONFLICT"
+	CodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	CodeUnprocessable        = "UNPROCESSABLE_ENTITY"
+	CodeTooManyRequests      = "TOO_MANY_REQUESTS"
+	CodeInternal             = "INTERNAL"
+	CodeUnavailable          = "UNAVAILABLE"
+)
 
 var (
-	ErrCalculate = errors.New("Calculate error")
+	ErrBadRequest           = New(CodeBadRequest, "Bad request")
+	ErrValidation           = New(CodeValidation, "Validation error")
+	ErrCalculate            = New(CodeCalculate, "Calculate error")
+	ErrUnauthorized         = New(CodeUnauthorized, "Unauthorized")
+	ErrForbidden            = New(CodeForbidden, "Forbidden")
+	ErrNotFound             = New(CodeNotFound, "Not found")
+	ErrMethodNotAllowed     = New(CodeMethodNotAllowed, "Method not allowed")
+	ErrConflict             = New(CodeConflict, "Conflict")
+	ErrPayloadTooLarge      = New(CodePayloadTooLarge, "Payload too large")
+	ErrUnsupportedMediaType = New(CodeUnsupportedMediaType, "Unsupported media type")
+	ErrUnprocessable        = New(CodeUnprocessable, "Unprocessable entity")
+	ErrTooManyRequests      = New(CodeTooManyRequests, "Too many requests")
+	ErrInternal             = New(CodeInternal, "Internal server error")
+	ErrUnavailable          = New(CodeUnavailable, "Service unavailable")
 )
 
-var code = map[error]string{
-	ErrCalculate: "CALCULATE",
+// DomainError is an error with a stable code. Errors with the same code
+// match with errors.Is, so a wrapped or re-worded copy of ErrNotFound is
+// still ErrNotFound, and the cause is reachable with errors.Unwrap.
+type DomainError struct {
+	Code    string
+	Message string
+	// Fields holds field errors of validation failures
+	Fields []*validation.Error
+	// Status is the HTTP status of an error converted from a status
+	// without a code, zero for the status of Code
+	Status int
+	Err    error
+}
+
+func New(code, message string) *DomainError {
+	return &DomainError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *DomainError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+	}
+	return e.Message
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DomainError) Is(target error) bool {
+	t, ok := target.(*DomainError)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err
+func (e *DomainError) Wrap(err error) *DomainError {
+	out := *e
+	out.Err = err
+	return &out
+}
+
+// WithMessage returns a copy of e with another message
+func (e *DomainError) WithMessage(format string, args ...interface{}) *DomainError {
+	out := *e
+	out.Message = fmt.Sprintf(format, args...)
+	return &out
+}
+
+// WithFields returns a copy of e with the field errors of result added
+func (e *DomainError) WithFields(result *validation.Result) *DomainError {
+	out := *e
+	out.Fields = append(append([]*validation.Error(nil), e.Fields...), result.Errors...)
+	return &out
+}
+
+// FromValidation converts a failed validation.Result into ErrValidation.
+// It returns nil for a valid result.
+func FromValidation(result *validation.Result) error {
+	if result == nil || result.IsValid() {
+		return nil
+	}
+	out := ErrValidation.WithFields(result)
+	if result.Details != "" {
+		out.Message = result.Details
+	}
+	return out
 }
 
+// CodeError returns the code of err, CodeInternal for errors without a code
+// and an empty string for nil.
 func CodeError(err error) string {
-	return code[err] //todo not implemented
+	if err == nil {
+		return ""
+	}
+	var de *DomainError
+	if errors.As(err, &de) {
+		return de.Code
+	}
+	return CodeInternal
 }
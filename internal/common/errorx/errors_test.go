@@ -0,0 +1,98 @@
+package errorx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+)
+
+type testMapping struct {
+	err    error
+	code   string
+	status int
+	grpc   codes.Code
+}
+
+func TestMapping(t *testing.T) {
+	testCases := []testMapping{
+		{err: ErrValidation, code: CodeValidation, status: http.StatusBadRequest, grpc: codes.InvalidArgument},
+		{err: ErrCalculate.Wrap(errors.New("no couriers")), code: CodeCalculate, status: http.StatusBadRequest, grpc: codes.FailedPrecondition},
+		{err: fmt.Errorf("handler: %w", ErrNotFound), code: CodeNotFound, status: http.StatusNotFound, grpc: codes.NotFound},
+		{err: ErrMethodNotAllowed, code: CodeMethodNotAllowed, status: http.StatusMethodNotAllowed, grpc: codes.Unimplemented},
+		{err: ErrTooManyRequests, code: CodeTooManyRequests, status: http.StatusTooManyRequests, grpc: codes.ResourceExhausted},
+		{err: errors.New("boom"), code: CodeInternal, status: http.StatusInternalServerError, grpc: codes.Internal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.code, CodeError(tc.err))
+			assert.Equal(t, tc.status, HTTPStatus(tc.err))
+			assert.Equal(t, tc.grpc, GRPCCode(tc.err))
+		})
+	}
+}
+
+func TestFromHTTPStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		code    string
+		want    string
+	}{
+		{status: http.StatusMethodNotAllowed, message: "Method Not Allowed", code: CodeMethodNotAllowed, want: ErrMethodNotAllowed.Message},
+		{status: http.StatusRequestEntityTooLarge, message: "Request Entity Too Large", code: CodePayloadTooLarge, want: ErrPayloadTooLarge.Message},
+		{status: http.StatusUnsupportedMediaType, code: CodeUnsupportedMediaType, want: ErrUnsupportedMediaType.Message},
+		{status: http.StatusUnprocessableEntity, message: "missing body", code: CodeUnprocessable, want: "missing body"},
+		{status: http.StatusTeapot, code: CodeBadRequest, want: "I'm a teapot"},
+		{status: http.StatusBadGateway, message: "Bad Gateway", code: CodeInternal, want: "Bad Gateway"},
+		{status: http.StatusGatewayTimeout, message: "upstream timed out", code: CodeInternal, want: "upstream timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			err := FromHTTPStatus(tt.status, tt.message)
+			assert.Equal(t, tt.code, CodeError(err))
+			assert.Equal(t, tt.status, HTTPStatus(err), "the status is kept")
+			assert.Equal(t, tt.want, err.Message)
+
+			res := NewErrorResponse(fmt.Errorf("middleware: %w", err))
+			assert.Equal(t, tt.status, res.StatusCode)
+		})
+	}
+}
+
+func TestIs(t *testing.T) {
+	cause := errors.New("no couriers")
+	err := fmt.Errorf("handler: %w", ErrCalculate.Wrap(cause))
+
+	assert.True(t, errors.Is(err, ErrCalculate))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, errors.Is(err, ErrInternal))
+
+	var de *DomainError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(de))
+}
+
+func TestFromValidation(t *testing.T) {
+	assert.Nil(t, FromValidation(validation.NewResult()))
+
+	err := FromValidation(validation.NewResult().AddFieldError("lat", validation.ErrorDetails{Message: "out of range", Code: "lat"}))
+	assert.True(t, errors.Is(err, ErrValidation))
+
+	res := NewErrorResponse(err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Len(t, res.DetailErrors, 1)
+}
+
+func TestNewErrorResponseHidesCause(t *testing.T) {
+	res := NewErrorResponse(errors.New("dial tcp 10.0.0.1:5432: refused"))
+	assert.Equal(t, CodeInternal, res.Code)
+	assert.Equal(t, ErrInternal.Message, res.Error)
+}
@@ -1,14 +1,18 @@
 package errorx
 
+// Error is the body of every failed response
 type Error struct {
 	Code         string      `json:"code"`
 	StatusCode   int         `json:"status_code"`
 	Error        string      `json:"error"`
-	DetailErrors interface{} `json:"detail_errors"`
+	DetailErrors interface{} `json:"detail_errors,omitempty"`
+	TraceID      string      `json:"trace_id,omitempty"`
 }
 
+// Success is the body of successful responses
 type Success struct {
 	Code    string      `json:"code"`
-	Message string      `json:"error"`
+	Message string      `json:"message"`
 	Details interface{} `json:"details"`
+	TraceID string      `json:"trace_id,omitempty"`
 }
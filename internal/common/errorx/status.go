@@ -0,0 +1,115 @@
+package errorx
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var httpStatus = map[string]int{
+	CodeBadRequest:           http.StatusBadRequest,
+	CodeValidation:           http.StatusBadRequest,
+	CodeCalculate:            http.StatusBadRequest,
+	CodeUnauthorized:         http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeNotFound:             http.StatusNotFound,
+	CodeMethodNotAllowed:     http.StatusMethodNotAllowed,
+	CodeConflict:             http.StatusConflict,
+	CodePayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	CodeUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	CodeUnprocessable:        http.StatusUnprocessableEntity,
+	CodeTooManyRequests:      http.StatusTooManyRequests,
+	CodeInternal:             http.StatusInternalServerError,
+	CodeUnavailable:          http.StatusServiceUnavailable,
+}
+
+var grpcCode = map[string]codes.Code{
+	CodeBadRequest:           codes.InvalidArgument,
+	CodeValidation:           codes.InvalidArgument,
+	CodeCalculate:            codes.FailedPrecondition,
+	CodeUnauthorized:         codes.Unauthenticated,
+	CodeForbidden:            codes.PermissionDenied,
+	CodeNotFound:             codes.NotFound,
+	CodeMethodNotAllowed:     codes.Unimplemented,
+	CodeConflict:             codes.AlreadyExists,
+	CodePayloadTooLarge:      codes.ResourceExhausted,
+	CodeUnsupportedMediaType: codes.InvalidArgument,
+	CodeUnprocessable:        codes.InvalidArgument,
+	CodeTooManyRequests:      codes.ResourceExhausted,
+	CodeInternal:             codes.Internal,
+	CodeUnavailable:          codes.Unavailable,
+}
+
+// HTTPStatus returns the HTTP status code err is rendered with
+func HTTPStatus(err error) int {
+	var de *DomainError
+	if errors.As(err, &de) && de.Status != 0 {
+		return de.Status
+	}
+	if s, ok := httpStatus[CodeError(err)]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the gRPC code err is returned with
+func GRPCCode(err error) codes.Code {
+	if c, ok := grpcCode[CodeError(err)]; ok {
+		return c
+	}
+	return codes.Internal
+}
+
+// GRPCStatus lets status.FromError convert domain errors
+func (e *DomainError) GRPCStatus() *status.Status {
+	return status.New(GRPCCode(e), e.Message)
+}
+
+// FromHTTPStatus returns the domain error of an HTTP status code, e.g. of
+// an error produced by a middleware. An empty message or the status text
+// of code keeps the default message of the error. Statuses without a code
+// are kept as the Status of ErrBadRequest or ErrInternal.
+func FromHTTPStatus(code int, message string) *DomainError {
+	for _, e := range []*DomainError{ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrMethodNotAllowed, ErrConflict,
+		ErrPayloadTooLarge, ErrUnsupportedMediaType, ErrUnprocessable, ErrTooManyRequests, ErrInternal, ErrUnavailable} {
+		if httpStatus[e.Code] == code {
+			if message == "" || message == http.StatusText(code) {
+				return e.Wrap(nil)
+			}
+			return e.WithMessage("%s", message)
+		}
+	}
+
+	out := ErrBadRequest.Wrap(nil)
+	if code >= http.StatusInternalServerError {
+		out = ErrInternal.Wrap(nil)
+	}
+	out.Status = code
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	if message != "" {
+		out.Message = message
+	}
+	return out
+}
+
+// NewErrorResponse renders err, the message of errors without a code is
+// hidden from clients
+func NewErrorResponse(err error) Error {
+	res := Error{
+		Code:       CodeError(err),
+		StatusCode: HTTPStatus(err),
+		Error:      ErrInternal.Message,
+	}
+	var de *DomainError
+	if errors.As(err, &de) {
+		res.Error = de.Message
+		if len(de.Fields) > 0 {
+			res.DetailErrors = de.Fields
+		}
+	}
+	return res
+}
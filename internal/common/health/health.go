@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+
+	lifecycleCheck = "lifecycle"
+
+	defaultTimeout = 2 * time.Second
+)
+
+var (
+	ErrNotReady = errors.New("service is shutting down or reloading")
+)
+
+// CheckFunc reports the state of a single component, nil means healthy.
+type CheckFunc func(ctx context.Context) error
+
+type Option func(*Checker)
+
+// WithTimeout limits the duration of every single check.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithReadiness sets the function consulted by Ready before running the
+// checks, e.g. lifecycle.Manager.Ready.
+func WithReadiness(ready func() bool) Option {
+	return func(c *Checker) {
+		c.ready = ready
+	}
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	// Optional checks are reported but never make the service unhealthy
+	Optional bool `json:"optional,omitempty"`
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	optional bool
+}
+
+// Checker runs the registered component checks for the health and
+// readiness endpoints.
+type Checker struct {
+	timeout time.Duration
+	ready   func() bool
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+func New(opts ...Option) *Checker {
+	c := &Checker{
+		timeout: defaultTimeout,
+		ready:   func() bool { return true },
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register adds a check whose failure makes the service unhealthy.
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.register(check{name: name, fn: fn})
+}
+
+// RegisterOptional adds a check which is only reported.
+func (c *Checker) RegisterOptional(name string, fn CheckFunc) {
+	c.register(check{name: name, fn: fn, optional: true})
+}
+
+func (c *Checker) register(ch check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, ch)
+}
+
+// Health runs all checks concurrently.
+func (c *Checker) Health(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+	for i, ch := range checks {
+		report.Checks[ch.name] = results[i]
+		if results[i].Status == StatusDown && !ch.optional {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// Ready is Health which additionally fails while the service is shutting
+// down or reloading.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := c.Health(ctx)
+
+	res := CheckResult{Status: StatusUp, Duration: time.Duration(0).String()}
+	if !c.ready() {
+		res.Status = StatusDown
+		res.Error = ErrNotReady.Error()
+		report.Status = StatusDown
+	}
+	report.Checks[lifecycleCheck] = res
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, ch check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ch.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := CheckResult{
+		Status:   StatusUp,
+		Duration: time.Since(start).String(),
+		Optional: ch.optional,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTest = errors.New("test error")
+
+func up(_ context.Context) error {
+	return nil
+}
+
+func down(_ context.Context) error {
+	return errTest
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []CheckFunc
+		optional []CheckFunc
+		want     Status
+	}{
+		{name: "no checks", want: StatusUp},
+		{name: "all up", required: []CheckFunc{up, up}, optional: []CheckFunc{up}, want: StatusUp},
+		{name: "required down", required: []CheckFunc{up, down}, want: StatusDown},
+		{name: "optional down", required: []CheckFunc{up}, optional: []CheckFunc{down}, want: StatusUp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			for i, fn := range tt.required {
+				c.Register(string(rune('a'+i)), fn)
+			}
+			for i, fn := range tt.optional {
+				c.RegisterOptional(string(rune('x'+i)), fn)
+			}
+
+			report := c.Health(context.Background())
+			assert.Equal(t, tt.want, report.Status)
+			assert.Len(t, report.Checks, len(tt.required)+len(tt.optional))
+		})
+	}
+
+	c := New()
+	c.Register("database", down)
+	c.RegisterOptional("tracing", down)
+	report := c.Health(context.Background())
+	assert.Equal(t, CheckResult{Status: StatusDown, Error: errTest.Error(), Duration: report.Checks["database"].Duration}, report.Checks["database"])
+	assert.True(t, report.Checks["tracing"].Optional)
+	assert.NotContains(t, report.Checks, lifecycleCheck, "health does not depend on the lifecycle")
+}
+
+func TestHealthTimeout(t *testing.T) {
+	c := New(WithTimeout(20 * time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	c.Register("stuck", func(_ context.Context) error {
+		<-release
+		return nil
+	})
+	c.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := c.Health(context.Background())
+	assert.Less(t, time.Since(start), time.Second, "checks run concurrently and are cut off")
+	assert.Equal(t, StatusDown, report.Status)
+	for _, name := range []string{"stuck", "slow"} {
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[name].Error, name)
+	}
+}
+
+func TestReady(t *testing.T) {
+	var ready int32 = 1
+	c := New(WithReadiness(func() bool { return atomic.LoadInt32(&ready) == 1 }))
+	c.Register("database", up)
+
+	report := c.Ready(context.Background())
+	assert.Equal(t, StatusUp, report.Status)
+	require.Contains(t, report.Checks, lifecycleCheck)
+	assert.Equal(t, StatusUp, report.Checks[lifecycleCheck].Status)
+
+	atomic.StoreInt32(&ready, 0)
+	report = c.Ready(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, ErrNotReady.Error(), report.Checks[lifecycleCheck].Error)
+	assert.Equal(t, StatusUp, c.Health(context.Background()).Status, "shutting down is not unhealthy")
+
+	atomic.StoreInt32(&ready, 1)
+	c.Register("couriers", down)
+	report = c.Ready(context.Background())
+	assert.Equal(t, StatusDown, report.Status, "failing checks make the service unready")
+	assert.Equal(t, StatusUp, report.Checks[lifecycleCheck].Status)
+}
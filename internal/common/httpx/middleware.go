@@ -45,6 +45,9 @@ type RequestInfo struct {
 	Code     int
 	Size     int64
 	Duration time.Duration
+
+	TraceID   string
+	RequestID string
 }
 
 func NewRequestInfo(r *http.Request) *RequestInfo {
@@ -60,24 +63,9 @@ func NewRequestInfo(r *http.Request) *RequestInfo {
 }
 
 // requestGetRemoteAddress returns ip address of the client making the request,
-// taking into account http proxies
+// taking into account the trusted http proxies
 func requestGetRemoteAddress(r *http.Request) string {
-	hdr := r.Header
-	hdrRealIP := hdr.Get(RealIPHeader)
-	hdrForwardedFor := hdr.Get(XForwardedForHeader)
-	if hdrRealIP == "" && hdrForwardedFor == "" {
-		return ipAddrFromRemoteAddr(r.RemoteAddr)
-	}
-	if hdrForwardedFor != "" {
-		// X-Forwarded-For is potentially a list of addresses separated with ","
-		parts := strings.Split(hdrForwardedFor, ",")
-		for i, p := range parts {
-			parts[i] = strings.TrimSpace(p)
-		}
-		// TODO: should return first non-local address
-		return parts[0]
-	}
-	return hdrRealIP
+	return ClientIP(r)
 }
 
 // Request.RemoteAddress contains port, which we want to remove i.e.:
@@ -0,0 +1,163 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const ForwardedHeader = "Forwarded"
+
+var ErrInvalidProxy = errors.New("invalid trusted proxy")
+
+// TrustedProxies extracts the client address of requests going through
+// proxies. Forwarding headers are only read from trusted proxies, and
+// their hops are walked from the right, the closest, to the left until an
+// address which is not a trusted proxy is found: entries left of it may
+// have been written by the client.
+type TrustedProxies struct {
+	nets []*net.IPNet
+}
+
+// ParseTrustedProxies accepts CIDRs and plain IP addresses. No proxy is
+// trusted for an empty list, so the peer address is always the client.
+func ParseTrustedProxies(cidrs []string) (*TrustedProxies, error) {
+	t := &TrustedProxies{}
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidProxy, c)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			t.nets = append(t.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidProxy, c)
+		}
+		t.nets = append(t.nets, n)
+	}
+	return t, nil
+}
+
+// Trusted reports whether ip belongs to a trusted proxy
+func (t *TrustedProxies) Trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range t.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client of r. The Forwarded header
+// (RFC 7239) is preferred over X-Forwarded-For, which is preferred over
+// X-Real-Ip.
+func (t *TrustedProxies) ClientIP(r *http.Request) string {
+	peer := ipAddrFromRemoteAddr(r.RemoteAddr)
+	if !t.Trusted(net.ParseIP(peer)) {
+		return peer
+	}
+
+	hops := forwardedFor(r.Header.Values(ForwardedHeader))
+	if len(hops) == 0 {
+		hops = splitList(r.Header.Values(XForwardedForHeader))
+	}
+	if len(hops) == 0 {
+		hops = splitList(r.Header.Values(RealIPHeader))
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// obfuscated or garbled hop, the last trusted proxy is the
+			// closest known address to the client
+			return client
+		}
+		client = ip.String()
+		if !t.Trusted(ip) {
+			return client
+		}
+	}
+	return client
+}
+
+// forwardedFor returns the for= addresses of Forwarded headers, e.g.
+// for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"
+func forwardedFor(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				out = append(out, forwardedNode(kv[1]))
+			}
+		}
+	}
+	return out
+}
+
+// forwardedNode strips quotes, brackets and port of a node, e.g.
+// "[2001:db8:cafe::17]:4711" => 2001:db8:cafe::17
+func forwardedNode(node string) string {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end != -1 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+var trustedProxies atomic.Value
+
+func init() {
+	trustedProxies.Store(&TrustedProxies{})
+}
+
+// SetTrustedProxies replaces the proxies trusted by ClientIP and
+// RequestInfo, no proxy is trusted until it is called
+func SetTrustedProxies(t *TrustedProxies) {
+	trustedProxies.Store(t)
+}
+
+// ClientIP returns the address of the client of r behind the trusted
+// proxies
+func ClientIP(r *http.Request) string {
+	return trustedProxies.Load().(*TrustedProxies).ClientIP(r)
+}
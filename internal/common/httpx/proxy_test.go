@@ -0,0 +1,96 @@
+package httpx
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClientIP struct {
+	name       string
+	remoteAddr string
+	headers    map[string]string
+	ip         string
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	require.NoError(t, err)
+
+	testCases := []testClientIP{
+		{name: "no proxy", remoteAddr: "203.0.113.9:4000", ip: "203.0.113.9"},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "203.0.113.9:4000",
+			headers:    map[string]string{XForwardedForHeader: "1.1.1.1"},
+			ip:         "203.0.113.9",
+		},
+		{
+			name:       "spoofed entries are skipped",
+			remoteAddr: "10.0.0.2:4000",
+			headers:    map[string]string{XForwardedForHeader: "6.6.6.6, 198.51.100.7, 10.1.1.1"},
+			ip:         "198.51.100.7",
+		},
+		{
+			name:       "all trusted",
+			remoteAddr: "10.0.0.2:4000",
+			headers:    map[string]string{XForwardedForHeader: "10.3.3.3, 192.0.2.1"},
+			ip:         "10.3.3.3",
+		},
+		{
+			name:       "real ip",
+			remoteAddr: "[2001:db8::1]:4000",
+			headers:    map[string]string{RealIPHeader: "198.51.100.7"},
+			ip:         "198.51.100.7",
+		},
+		{
+			name:       "forwarded is preferred",
+			remoteAddr: "10.0.0.2:4000",
+			headers: map[string]string{
+				ForwardedHeader:     `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, For=192.0.2.1;by=10.0.0.2`,
+				XForwardedForHeader: "1.1.1.1",
+			},
+			ip: "6.6.6.6",
+		},
+		{
+			name:       "forwarded ipv4 with port",
+			remoteAddr: "10.0.0.2:4000",
+			headers:    map[string]string{ForwardedHeader: `for="198.51.100.7:5000"`},
+			ip:         "198.51.100.7",
+		},
+		{
+			name:       "obfuscated hop",
+			remoteAddr: "10.0.0.2:4000",
+			headers:    map[string]string{ForwardedHeader: `for=198.51.100.7, for=_hidden, for=10.9.9.9`},
+			ip:         "10.9.9.9",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tc.ip, proxies.ClientIP(r))
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	_, err := ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+	_, err = ParseTrustedProxies([]string{"localhost"})
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+
+	none, err := ParseTrustedProxies(nil)
+	require.NoError(t, err)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "127.0.0.1:4000"
+	r.Header.Set(XForwardedForHeader, "1.1.1.1")
+	assert.Equal(t, "127.0.0.1", none.ClientIP(r))
+	assert.Equal(t, "127.0.0.1", ClientIP(r), "no proxy is trusted by default")
+}
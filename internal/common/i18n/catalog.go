@@ -0,0 +1,177 @@
+package i18n
+
+import (
+	"golang.org/x/text/language"
+)
+
+// catalogs maps error codes to messages per locale. Keys are errorx codes,
+// validation codes and go-playground validator tags; {param} is the
+// parameter of the tag, e.g. 3 in min=3.
+var catalogs = map[language.Tag]map[string]string{
+	English: {
+		// errorx
+		"BAD_REQUEST":            "Bad request",
+		"VALIDATION":             "Validation error",
+		"CALCULATE":              "Calculate error",
+		"UNAUTHORIZED":           "Unauthorized",
+		"FORBIDDEN":              "Forbidden",
+		"NOT_FOUND":              "Not found",
+		"METHOD_NOT_ALLOWED":     "Method not allowed",
+		"CONFLICT":               "Conflict",
+		"PAYLOAD_TOO_LARGE":      "Payload too large",
+		"UNSUPPORTED_MEDIA_TYPE": "Unsupported media type",
+		"UNPROCESSABLE_ENTITY":   "Unprocessable entity",
+		"TOO_MANY_REQUESTS":      "Too many requests",
+		"INTERNAL":               "Internal server error",
+		"UNAVAILABLE":            "Service unavailable",
+
+		// validation
+		"invalid_duration": "duration is invalid",
+		"invalid_level":    "log level is invalid",
+
+		// request and account validation
+		"invalid_body":                        "request body is invalid",
+		"db_operation":                        "database operation failed",
+		"both_email_and_phone":                "can't provide both email and phone during registration",
+		"captcha":                             "captcha is invalid",
+		"invalid_value":                       "{details}",
+		"either_phone_or_email":               "either phone or e-mail must be provided",
+		"empty_birth_date":                    "empty birthday",
+		"not_only_letters":                    "field must contain only letters",
+		"user_already_exists":                 "user already exists",
+		"user_not_found":                      "user not found",
+		"unauthorized":                        "unauthorized",
+		"empty_password":                      "empty password provided",
+		"invalid_password":                    "password must contain at least 1 lowercased letter, 1 capital letter, 1 digit, 1 special char and be minimum 8 chars long",
+		"rules_not_accepted":                  "rules were not accepted",
+		"name_too_short":                      "name cannot be less than 2 characters",
+		"too_young_age":                       "age must be more than 18 years",
+		"wrong_phone_format":                  "wrong phone format: it should contain only digits",
+		"invalid_email":                       "email is empty or has invalid format",
+		"unknown_country":                     "such country does not exist",
+		"invalid_phone":                       "phone is empty",
+		"invalid_ip_address":                  "ip_address is empty or has invalid format: {ip}",
+		"empty_device":                        "empty device provided",
+		"empty_refresh_token":                 "empty refresh token provided",
+		"invalid_anti_phishing_code":          "anti-phishing code is invalid",
+		"invalid_country_calling_code_format": "country calling code format is invalid",
+		"wrong_country_calling_code":          "country calling code does not match selected country",
+		"invalid_page_limit":                  "page limit is invalid",
+		"invalid_order_column":                "order column is invalid",
+		"invalid_otp_code":                    "invalid otp code provided",
+		"invalid_action":                      "action is invalid",
+		"invalid_2fa_method":                  "method is invalid",
+		"invalid_code":                        "code is invalid",
+		"invalid_key":                         "key is empty",
+		"invalid_reset_token":                 "reset-token is invalid",
+		"invalid_confirm_password":            "confirm password is invalid",
+		"invalid_keys":                        "phone or email method is missing",
+
+		// geo
+		"lat":                       "latitude must be a number between -90 and 90",
+		"lng":                       "longitude must be a number between -180 and 180",
+		"not_null_island":           "location 0,0 is not accepted",
+		"polygon":                   "polygon must have at least 3 distinct points and a non-zero area",
+		"polygon_self_intersection": "polygon edges must not intersect",
+		"bbox":                      "bounding box corners are invalid",
+		"max_points":                "at most {param} points are accepted",
+
+		// validator tags
+		"required": "is required",
+		"min":      "must be at least {param}",
+		"max":      "must be at most {param}",
+		"len":      "must have length {param}",
+		"gte":      "must be greater than or equal to {param}",
+		"lte":      "must be less than or equal to {param}",
+		"gt":       "must be greater than {param}",
+		"lt":       "must be less than {param}",
+		"oneof":    "must be one of {param}",
+		"email":    "must be a valid e-mail",
+		"numeric":  "must be numeric",
+		"url":      "must be a valid URL",
+		"uuid":     "must be a valid UUID",
+	},
+	Persian: {
+		// errorx
+		"BAD_REQUEST":            "درخواست نامعتبر است",
+		"VALIDATION":             "خطای اعتبارسنجی",
+		"CALCULATE":              "خطا در محاسبه",
+		"UNAUTHORIZED":           "احراز هویت نشده است",
+		"FORBIDDEN":              "دسترسی مجاز نیست",
+		"NOT_FOUND":              "یافت نشد",
+		"METHOD_NOT_ALLOWED":     "متد مجاز نیست",
+		"CONFLICT":               "تداخل",
+		"PAYLOAD_TOO_LARGE":      "حجم درخواست بیش از حد مجاز است",
+		"UNSUPPORTED_MEDIA_TYPE": "نوع محتوا پشتیبانی نمی‌شود",
+		"UNPROCESSABLE_ENTITY":   "درخواست قابل پردازش نیست",
+		"TOO_MANY_REQUESTS":      "تعداد درخواست‌ها بیش از حد مجاز است",
+		"INTERNAL":               "خطای داخلی سرور",
+		"UNAVAILABLE":            "سرویس در دسترس نیست",
+
+		// validation
+		"invalid_duration": "مدت زمان نامعتبر است",
+		"invalid_level":    "سطح لاگ نامعتبر است",
+
+		// request and account validation
+		"invalid_body":                        "بدنه درخواست نامعتبر است",
+		"db_operation":                        "عملیات پایگاه داده ناموفق بود",
+		"both_email_and_phone":                "هنگام ثبت‌نام نمی‌توان هم ایمیل و هم تلفن وارد کرد",
+		"captcha":                             "کپچا نامعتبر است",
+		"invalid_value":                       "مقدار نامعتبر است: {details}",
+		"either_phone_or_email":               "باید تلفن یا ایمیل وارد شود",
+		"empty_birth_date":                    "تاریخ تولد خالی است",
+		"not_only_letters":                    "این فیلد باید فقط شامل حروف باشد",
+		"user_already_exists":                 "کاربر از قبل وجود دارد",
+		"user_not_found":                      "کاربر یافت نشد",
+		"unauthorized":                        "احراز هویت نشده است",
+		"empty_password":                      "رمز عبور خالی است",
+		"invalid_password":                    "رمز عبور باید حداقل ۸ کاراکتر و شامل حداقل ۱ حرف کوچک، ۱ حرف بزرگ، ۱ رقم و ۱ کاراکتر خاص باشد",
+		"rules_not_accepted":                  "قوانین پذیرفته نشده است",
+		"name_too_short":                      "نام نمی‌تواند کمتر از ۲ کاراکتر باشد",
+		"too_young_age":                       "سن باید بیشتر از ۱۸ سال باشد",
+		"wrong_phone_format":                  "قالب تلفن نادرست است: باید فقط شامل ارقام باشد",
+		"invalid_email":                       "ایمیل خالی است یا قالب نامعتبر دارد",
+		"unknown_country":                     "چنین کشوری وجود ندارد",
+		"invalid_phone":                       "تلفن خالی است",
+		"invalid_ip_address":                  "آدرس IP خالی است یا قالب نامعتبر دارد: {ip}",
+		"empty_device":                        "دستگاه خالی است",
+		"empty_refresh_token":                 "توکن تازه‌سازی خالی است",
+		"invalid_anti_phishing_code":          "کد ضد فیشینگ نامعتبر است",
+		"invalid_country_calling_code_format": "قالب پیش‌شماره کشور نامعتبر است",
+		"wrong_country_calling_code":          "پیش‌شماره با کشور انتخاب‌شده مطابقت ندارد",
+		"invalid_page_limit":                  "محدودیت صفحه نامعتبر است",
+		"invalid_order_column":                "ستون مرتب‌سازی نامعتبر است",
+		"invalid_otp_code":                    "کد یکبار مصرف نامعتبر است",
+		"invalid_action":                      "عملیات نامعتبر است",
+		"invalid_2fa_method":                  "روش نامعتبر است",
+		"invalid_code":                        "کد نامعتبر است",
+		"invalid_key":                         "کلید خالی است",
+		"invalid_reset_token":                 "توکن بازنشانی نامعتبر است",
+		"invalid_confirm_password":            "تکرار رمز عبور نامعتبر است",
+		"invalid_keys":                        "روش تلفن یا ایمیل وارد نشده است",
+
+		// geo
+		"lat":                       "عرض جغرافیایی باید عددی بین ۹۰- و ۹۰ باشد",
+		"lng":                       "طول جغرافیایی باید عددی بین ۱۸۰- و ۱۸۰ باشد",
+		"not_null_island":           "موقعیت ۰،۰ پذیرفته نمی‌شود",
+		"polygon":                   "چندضلعی باید حداقل ۳ نقطه متمایز و مساحت غیر صفر داشته باشد",
+		"polygon_self_intersection": "اضلاع چندضلعی نباید یکدیگر را قطع کنند",
+		"bbox":                      "گوشه‌های محدوده نامعتبر است",
+		"max_points":                "حداکثر {param} نقطه پذیرفته می‌شود",
+
+		// validator tags
+		"required": "الزامی است",
+		"min":      "باید حداقل {param} باشد",
+		"max":      "باید حداکثر {param} باشد",
+		"len":      "طول باید {param} باشد",
+		"gte":      "باید بزرگتر یا مساوی {param} باشد",
+		"lte":      "باید کوچکتر یا مساوی {param} باشد",
+		"gt":       "باید بزرگتر از {param} باشد",
+		"lt":       "باید کوچکتر از {param} باشد",
+		"oneof":    "باید یکی از {param} باشد",
+		"email":    "باید یک ایمیل معتبر باشد",
+		"numeric":  "باید عددی باشد",
+		"url":      "باید یک آدرس معتبر باشد",
+		"uuid":     "باید یک UUID معتبر باشد",
+	},
+}
@@ -0,0 +1,113 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+)
+
+var (
+	English = language.English
+	Persian = language.Persian
+
+	// Default is used when no requested locale is supported and for codes
+	// missing from the catalog of the negotiated locale
+	Default = English
+
+	supported = []language.Tag{English, Persian}
+	matcher   = language.NewMatcher(supported)
+)
+
+type localeKey struct{}
+
+// Negotiate returns the supported locale best matching an Accept-Language
+// header, Default when nothing matches.
+func Negotiate(acceptLanguage string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, idx, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return supported[idx]
+}
+
+func WithLocale(ctx context.Context, locale language.Tag) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext returns the locale stored with WithLocale, Default otherwise
+func FromContext(ctx context.Context) language.Tag {
+	if locale, ok := ctx.Value(localeKey{}).(language.Tag); ok {
+		return locale
+	}
+	return Default
+}
+
+// Message returns the message of code in locale, falling back to English.
+// Placeholders written as {name} are replaced by params. The second return
+// value is false when no catalog knows the code.
+func Message(locale language.Tag, code string, params map[string]string) (string, bool) {
+	msg, ok := catalogs[locale][code]
+	if !ok {
+		msg, ok = catalogs[Default][code]
+	}
+	if !ok {
+		return "", false
+	}
+	if len(params) == 0 {
+		return msg, true
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(msg), true
+}
+
+// Error translates the message of an errorx code. Messages other than the
+// English default of the code carry specific details and are kept as is.
+func Error(locale language.Tag, code, message string) string {
+	if message != catalogs[Default][code] {
+		return message
+	}
+	if msg, ok := Message(locale, code, nil); ok {
+		return msg
+	}
+	return message
+}
+
+// Fields returns translated copies of validation field errors, messages of
+// unknown codes are kept.
+func Fields(locale language.Tag, fields []*validation.Error) []*validation.Error {
+	out := make([]*validation.Error, 0, len(fields))
+	for _, f := range fields {
+		codes := make([]validation.ErrorDetails, 0, len(f.Codes))
+		for _, ed := range f.Codes {
+			if msg, ok := Message(locale, ed.Code, ed.Params); ok {
+				ed.Message = msg
+			}
+			codes = append(codes, ed)
+		}
+		out = append(out, &validation.Error{Name: f.Name, Codes: codes})
+	}
+	return out
+}
+
+// Result returns a translated copy of result
+func Result(locale language.Tag, result *validation.Result) *validation.Result {
+	if result == nil {
+		return nil
+	}
+	out := *result
+	if msg, ok := Message(locale, result.Code, nil); ok && result.Details != "" {
+		out.Details = msg
+	}
+	out.Errors = Fields(locale, result.Errors)
+	return &out
+}
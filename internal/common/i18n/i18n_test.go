@@ -0,0 +1,114 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+)
+
+type testNegotiate struct {
+	header string
+	locale language.Tag
+}
+
+func TestNegotiate(t *testing.T) {
+	testCases := []testNegotiate{
+		{header: "", locale: English},
+		{header: "fa", locale: Persian},
+		{header: "fa-IR,fa;q=0.9,en;q=0.8", locale: Persian},
+		{header: "en-US,en;q=0.9", locale: English},
+		{header: "de-DE", locale: English},
+		{header: "de;q=0.9,fa;q=0.5", locale: Persian},
+		{header: "not a header;;", locale: English},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			assert.Equal(t, tc.locale, Negotiate(tc.header))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	assert.Equal(t, "یافت نشد", Error(Persian, "NOT_FOUND", "Not found"))
+	assert.Equal(t, "route not found", Error(Persian, "NOT_FOUND", "route not found"))
+	assert.Equal(t, "Not found", Error(English, "NOT_FOUND", "Not found"))
+}
+
+func TestFields(t *testing.T) {
+	fields := []*validation.Error{{
+		Name: "lat",
+		Codes: []validation.ErrorDetails{
+			{Message: "must be at least 3", Code: "min", Params: map[string]string{"param": "3"}},
+			{Message: "custom", Code: "not_implemented"},
+		},
+	}}
+
+	out := Fields(Persian, fields)
+	assert.Equal(t, "باید حداقل 3 باشد", out[0].Codes[0].Message)
+	assert.Equal(t, "custom", out[0].Codes[1].Message)
+	// the input is not modified
+	assert.Equal(t, "must be at least 3", fields[0].Codes[0].Message)
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for code := range catalogs[Default] {
+			_, ok := catalog[code]
+			assert.True(t, ok, "%s is missing %s", locale, code)
+		}
+	}
+}
+
+func TestValidationHelpers(t *testing.T) {
+	helpers := []validation.ErrorDetails{
+		validation.EitherPhoneOrEmail(), validation.EmptyBirthDate(), validation.NotOnlyLetters(),
+		validation.UserAlreadyExists(), validation.UserNotFound(), validation.Unauthorized(),
+		validation.EmptyPassword(), validation.InvalidPassword(), validation.RulesNotAccepted(),
+		validation.NameIsTooShort(), validation.TooYoungAge(), validation.WrongPhoneFormat(),
+		validation.InvalidEmail(), validation.UnknownCountry(), validation.InvalidPhone(),
+		validation.InvalidIPAddress("10.0.0.300"), validation.EmptyDevice(), validation.EmptyRefreshToken(),
+		validation.InvalidAntiPhishingCode(), validation.InvalidCountryCallingCodeFormat(),
+		validation.WrongCountryCallingCode(), validation.InvalidPageLimit(), validation.InvalidOrderColumn(),
+		validation.InvalidOtpCode(), validation.InvalidAction(), validation.Invalid2FAMethod(),
+		validation.InvalidCode(), validation.InvalidKey(), validation.InvalidResetToken(),
+		validation.InvalidConfirmPassword(), validation.InvalidKeys(),
+	}
+	unmarshal := validation.UnmarshalDetailedError(errors.New(`code=400, message=Unmarshal type error, field=lat, internal=expected=float64, got=string`))
+	helpers = append(helpers, unmarshal.Errors[0].Codes[0])
+
+	for _, ed := range helpers {
+		t.Run(ed.Code, func(t *testing.T) {
+			en, ok := Message(English, ed.Code, ed.Params)
+			assert.True(t, ok)
+			assert.Equal(t, ed.Message, en, "the English message matches the helper")
+
+			out := Fields(Persian, []*validation.Error{{Name: "field", Codes: []validation.ErrorDetails{ed}}})
+			fa := out[0].Codes[0].Message
+			assert.NotEqual(t, ed.Message, fa)
+			assert.NotContains(t, fa, "{", "every placeholder is filled")
+		})
+	}
+
+	assert.Contains(t, Fields(Persian, []*validation.Error{{Name: "ip", Codes: []validation.ErrorDetails{validation.InvalidIPAddress("10.0.0.300")}}})[0].Codes[0].Message, "10.0.0.300")
+}
+
+func TestValidationResults(t *testing.T) {
+	results := []*validation.Result{
+		validation.UnmarshalError(errors.New("unexpected EOF")),
+		validation.DBOperationError(errors.New("dial tcp 10.0.0.1:5432: refused")),
+		validation.BothEmailAndPhoneProvided(),
+		validation.CaptchaError(errors.New("captcha expired")),
+	}
+	for _, r := range results {
+		t.Run(r.Code, func(t *testing.T) {
+			msg, ok := Message(Persian, r.Code, nil)
+			assert.True(t, ok)
+			assert.Equal(t, msg, Result(Persian, r).Details)
+		})
+	}
+}
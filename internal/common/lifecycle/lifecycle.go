@@ -0,0 +1,289 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+)
+
+const (
+	ExitOK              = 0
+	ExitError           = 1
+	ExitShutdownTimeout = 2
+
+	defaultShutdownTimeout = 15 * time.Second
+)
+
+var (
+	ErrShutdownTimeout = errors.New("shutdown deadline exceeded")
+)
+
+// Hook is a named step executed on shutdown or reload.
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+type Option func(*Manager)
+
+// WithShutdownTimeout sets the deadline for shutdown hooks and background
+// workers to finish once termination has been requested.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		if timeout > 0 {
+			m.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithDrainDelay keeps serving for the given duration after readiness has
+// been flipped to failing, so load balancers stop routing new traffic
+// before the listeners are closed.
+func WithDrainDelay(delay time.Duration) Option {
+	return func(m *Manager) {
+		m.drainDelay = delay
+	}
+}
+
+// Manager owns the process lifecycle: it handles termination signals,
+// tracks readiness and drains servers and background workers on shutdown.
+type Manager struct {
+	logger          *loggerx.Logger
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+
+	ready int32
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+	errCh   chan error
+
+	mu            sync.Mutex
+	shutdownHooks []namedHook
+	reloadHooks   []namedHook
+	signalHooks   map[os.Signal][]func()
+}
+
+func New(logger *loggerx.Logger, opts ...Option) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		logger:          logger,
+		shutdownTimeout: defaultShutdownTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+		errCh:           make(chan error, 1),
+		signalHooks:     make(map[os.Signal][]func()),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Go runs fn as a background worker. The context passed to fn is cancelled
+// after the shutdown hooks have run, and fn must return before the shutdown
+// deadline. A worker returning an error triggers shutdown.
+func (m *Manager) Go(name string, fn func(ctx context.Context) error) {
+	m.workers.Add(1)
+	go func() {
+		defer m.workers.Done()
+		if err := fn(m.ctx); err != nil {
+			m.logger.Error("worker failed", loggerx.String("worker", name), loggerx.Error(err))
+			select {
+			case m.errCh <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// OnShutdown registers a hook which stops accepting work and drains what is
+// in flight, e.g. http.Server.Shutdown. Hooks run in reverse order of
+// registration.
+func (m *Manager) OnShutdown(name string, hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shutdownHooks = append(m.shutdownHooks, namedHook{name: name, hook: hook})
+}
+
+// OnReload registers a hook executed on SIGHUP while readiness is failing.
+func (m *Manager) OnReload(name string, hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloadHooks = append(m.reloadHooks, namedHook{name: name, hook: hook})
+}
+
+// OnSignal registers fn to be called when the process receives sig,
+// e.g. SIGUSR1 to toggle debug logging. Termination signals are handled
+// by Run and must not be registered.
+func (m *Manager) OnSignal(sig os.Signal, fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signalHooks[sig] = append(m.signalHooks[sig], fn)
+}
+
+// Ready reports whether the process accepts new traffic.
+func (m *Manager) Ready() bool {
+	return atomic.LoadInt32(&m.ready) == 1
+}
+
+func (m *Manager) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&m.ready, v)
+}
+
+// Run marks the process as ready and blocks until SIGINT, SIGTERM or a
+// failing worker, then shuts down. SIGHUP runs the reload hooks and the
+// signals registered with OnSignal run their functions.
+//
+// Kubernetes and `docker stop` send SIGTERM and follow up with SIGKILL once
+// the grace period is over, so the shutdown timeout must stay below it:
+// https://cloud.google.com/blog/products/containers-kubernetes/kubernetes-best-practices-terminating-with-grace
+func (m *Manager) Run() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append(m.signals(), syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)...)
+	defer signal.Stop(signals)
+
+	m.SetReady(true)
+
+	var cause error
+loop:
+	for {
+		select {
+		case sig := <-signals:
+			m.logger.Info("signal received", loggerx.String("signal", sig.String()))
+			switch sig {
+			case syscall.SIGINT, syscall.SIGTERM:
+				break loop
+			case syscall.SIGHUP:
+				m.reload()
+			default:
+				m.handleSignal(sig)
+			}
+		case cause = <-m.errCh:
+			break loop
+		}
+	}
+
+	if err := m.Shutdown(); err != nil {
+		return err
+	}
+	return cause
+}
+
+func (m *Manager) signals() []os.Signal {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]os.Signal, 0, len(m.signalHooks))
+	for sig := range m.signalHooks {
+		out = append(out, sig)
+	}
+	return out
+}
+
+func (m *Manager) handleSignal(sig os.Signal) {
+	m.mu.Lock()
+	hooks := append([]func(){}, m.signalHooks[sig]...)
+	m.mu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+func (m *Manager) reload() {
+	m.SetReady(false)
+	defer m.SetReady(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	for _, h := range m.hooks(false) {
+		if err := h.hook(ctx); err != nil {
+			m.logger.Error("reload hook failed", loggerx.String("hook", h.name), loggerx.Error(err))
+		}
+	}
+}
+
+// Shutdown flips readiness to failing, runs the shutdown hooks, cancels the
+// background workers and waits for them until the shutdown deadline.
+// Error ErrShutdownTimeout
+func (m *Manager) Shutdown() error {
+	m.SetReady(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	if m.drainDelay > 0 {
+		select {
+		case <-time.After(m.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	var firstErr error
+	hooks := m.hooks(true)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		err := hooks[i].hook(ctx)
+		if err == nil {
+			continue
+		}
+		m.logger.Error("shutdown hook failed", loggerx.String("hook", hooks[i].name), loggerx.Error(err))
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrShutdownTimeout
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", hooks[i].name, err)
+		}
+	}
+
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ErrShutdownTimeout
+	}
+
+	return firstErr
+}
+
+func (m *Manager) hooks(shutdown bool) []namedHook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if shutdown {
+		return append([]namedHook(nil), m.shutdownHooks...)
+	}
+	return append([]namedHook(nil), m.reloadHooks...)
+}
+
+// ExitCode maps the error returned by Run to the process exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrShutdownTimeout):
+		return ExitShutdownTimeout
+	default:
+		return ExitError
+	}
+}
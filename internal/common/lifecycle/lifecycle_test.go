@@ -0,0 +1,208 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+)
+
+var errTest = errors.New("test error")
+
+// events records the order of hooks and workers
+type events struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log = append(e.log, event)
+}
+
+func (e *events) all() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.log...)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	m := New(loggerx.NewTestLogger())
+	var ev events
+	m.SetReady(true)
+
+	m.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		ev.add("worker stopped")
+		return nil
+	})
+	for _, name := range []string{"tracing", "database", "http"} {
+		name := name
+		m.OnShutdown(name, func(_ context.Context) error {
+			ev.add(fmt.Sprintf("%s ready=%v", name, m.Ready()))
+			return nil
+		})
+	}
+
+	require.NoError(t, m.Shutdown())
+	assert.Equal(t, []string{
+		"http ready=false",
+		"database ready=false",
+		"tracing ready=false",
+		"worker stopped",
+	}, ev.all(), "hooks run in reverse order before the workers are cancelled")
+}
+
+func TestShutdownDrainDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	m := New(loggerx.NewTestLogger(), WithDrainDelay(delay))
+	m.SetReady(true)
+
+	var ranAfter time.Duration
+	start := time.Now()
+	m.OnShutdown("http", func(_ context.Context) error {
+		ranAfter = time.Since(start)
+		return nil
+	})
+	go func() {
+		time.Sleep(delay / 2)
+		assert.False(t, m.Ready(), "readiness fails while draining")
+	}()
+
+	require.NoError(t, m.Shutdown())
+	assert.GreaterOrEqual(t, ranAfter, delay)
+}
+
+func TestShutdownErrors(t *testing.T) {
+	t.Run("hook error", func(t *testing.T) {
+		m := New(loggerx.NewTestLogger())
+		var ran []string
+		m.OnShutdown("first", func(_ context.Context) error {
+			ran = append(ran, "first")
+			return errors.New("other error")
+		})
+		m.OnShutdown("second", func(_ context.Context) error {
+			ran = append(ran, "second")
+			return errTest
+		})
+
+		err := m.Shutdown()
+		assert.ErrorIs(t, err, errTest)
+		assert.Contains(t, err.Error(), "second")
+		assert.Equal(t, []string{"second", "first"}, ran, "a failing hook does not stop the others")
+		assert.Equal(t, ExitError, ExitCode(err))
+	})
+
+	t.Run("hook timeout", func(t *testing.T) {
+		m := New(loggerx.NewTestLogger(), WithShutdownTimeout(20*time.Millisecond))
+		m.OnShutdown("http", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		err := m.Shutdown()
+		assert.ErrorIs(t, err, ErrShutdownTimeout)
+		assert.Equal(t, ExitShutdownTimeout, ExitCode(err))
+	})
+
+	t.Run("worker timeout", func(t *testing.T) {
+		m := New(loggerx.NewTestLogger(), WithShutdownTimeout(20*time.Millisecond))
+		release := make(chan struct{})
+		defer close(release)
+		m.Go("stuck", func(_ context.Context) error {
+			<-release
+			return nil
+		})
+
+		assert.ErrorIs(t, m.Shutdown(), ErrShutdownTimeout)
+	})
+}
+
+func TestRunSignals(t *testing.T) {
+	m := New(loggerx.NewTestLogger())
+	var ev events
+	usr1 := make(chan struct{}, 1)
+	reloaded := make(chan struct{}, 1)
+	m.OnSignal(syscall.SIGUSR1, func() {
+		ev.add("usr1")
+		usr1 <- struct{}{}
+	})
+	m.OnReload("tls", func(_ context.Context) error {
+		ev.add(fmt.Sprintf("reload ready=%v", m.Ready()))
+		reloaded <- struct{}{}
+		return errTest
+	})
+	m.OnShutdown("http", func(_ context.Context) error {
+		ev.add("shutdown")
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run()
+	}()
+	require.Eventually(t, m.Ready, time.Second, time.Millisecond)
+
+	signal := func(sig syscall.Signal) {
+		require.NoError(t, syscall.Kill(os.Getpid(), sig))
+	}
+	signal(syscall.SIGUSR1)
+	waitFor(t, usr1)
+	signal(syscall.SIGHUP)
+	waitFor(t, reloaded)
+	require.Eventually(t, m.Ready, time.Second, time.Millisecond, "ready again after a failed reload")
+
+	signal(syscall.SIGTERM)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Equal(t, ExitOK, ExitCode(err))
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after SIGTERM")
+	}
+	assert.Equal(t, []string{"usr1", "reload ready=false", "shutdown"}, ev.all())
+	assert.False(t, m.Ready())
+}
+
+func TestRunWorkerError(t *testing.T) {
+	m := New(loggerx.NewTestLogger())
+	shutdown := false
+	m.OnShutdown("http", func(_ context.Context) error {
+		shutdown = true
+		return nil
+	})
+	m.Go("watch", func(_ context.Context) error {
+		return errTest
+	})
+
+	err := m.Run()
+	assert.ErrorIs(t, err, errTest)
+	assert.Contains(t, err.Error(), "watch")
+	assert.True(t, shutdown, "a failing worker shuts the process down")
+	assert.Equal(t, ExitError, ExitCode(err))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitError, ExitCode(errTest))
+	assert.Equal(t, ExitShutdownTimeout, ExitCode(fmt.Errorf("http: %w", ErrShutdownTimeout)))
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
@@ -16,7 +16,7 @@ const (
 	TimeType
 	ErrorType
 
-	modeDev = "development"
+	traceIDKey = "trace_id"
 )
 
 type Field struct {
@@ -50,6 +50,21 @@ func String(key string, value string) Field {
 	}
 }
 
+// TraceID is the field correlating log entries with traces
+func TraceID(id string) Field {
+	return String(traceIDKey, id)
+}
+
+// SentryReported marks an entry whose event has already been sent to
+// sentry, so the sentry core skips it
+func SentryReported() Field {
+	return Field{
+		Key:   reportedFieldName,
+		Type:  IntType,
+		Value: 1,
+	}
+}
+
 func Error(err error) Field {
 	return Field{
 		Key:   errorKeyName,
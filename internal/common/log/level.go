@@ -0,0 +1,206 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels holds the level of a logger tree: a base level and overrides
+// for named loggers, all of them can be changed at runtime. Temporary
+// changes revert to the persistent levels, which only changes without a
+// ttl update.
+type levels struct {
+	configured zapcore.Level
+	base       zap.AtomicLevel
+
+	mu     sync.RWMutex
+	named  map[string]zapcore.Level
+	timers map[string]*time.Timer
+
+	persistentBase  zapcore.Level
+	persistentNamed map[string]zapcore.Level
+}
+
+func newLevels(level zapcore.Level) *levels {
+	return &levels{
+		configured:      level,
+		base:            zap.NewAtomicLevelAt(level),
+		named:           make(map[string]zapcore.Level),
+		timers:          make(map[string]*time.Timer),
+		persistentBase:  level,
+		persistentNamed: make(map[string]zapcore.Level),
+	}
+}
+
+func (ls *levels) level(name string) zapcore.Level {
+	if name != "" {
+		ls.mu.RLock()
+		lvl, ok := ls.named[name]
+		ls.mu.RUnlock()
+		if ok {
+			return lvl
+		}
+	}
+	return ls.base.Level()
+}
+
+// set changes the level of name, empty name is the base level. A positive
+// ttl restores the persistent level once it elapses, so overlapping
+// temporary changes never make each other permanent.
+func (ls *levels) set(name string, lvl zapcore.Level, ttl time.Duration) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if t, ok := ls.timers[name]; ok {
+		t.Stop()
+		delete(ls.timers, name)
+	}
+
+	if name == "" {
+		ls.base.SetLevel(lvl)
+	} else {
+		ls.named[name] = lvl
+	}
+
+	if ttl <= 0 {
+		if name == "" {
+			ls.persistentBase = lvl
+		} else {
+			ls.persistentNamed[name] = lvl
+		}
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		ls.mu.Lock()
+		defer ls.mu.Unlock()
+		if ls.timers[name] != timer {
+			// replaced by a newer change
+			return
+		}
+		delete(ls.timers, name)
+		ls.revert(name)
+	})
+	ls.timers[name] = timer
+}
+
+// revert restores the persistent level of name, named loggers without
+// one inherit the base level again. ls.mu must be held.
+func (ls *levels) revert(name string) {
+	if name == "" {
+		ls.base.SetLevel(ls.persistentBase)
+		return
+	}
+	if lvl, ok := ls.persistentNamed[name]; ok {
+		ls.named[name] = lvl
+	} else {
+		delete(ls.named, name)
+	}
+}
+
+func (ls *levels) all() map[string]string {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	out := make(map[string]string, len(ls.named)+1)
+	out[""] = ls.base.Level().String()
+	for name, lvl := range ls.named {
+		out[name] = lvl.String()
+	}
+	return out
+}
+
+// namedLevel is the LevelEnabler of a named logger
+type namedLevel struct {
+	levels *levels
+	name   string
+}
+
+func (n namedLevel) Enabled(lvl zapcore.Level) bool {
+	return n.levels.level(n.name).Enabled(lvl)
+}
+
+// levelCore filters entries of the wrapped core by a level which can
+// change at runtime.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func newLevelCore(core zapcore.Core, enabler zapcore.LevelEnabler) zapcore.Core {
+	if lc, ok := core.(*levelCore); ok {
+		core = lc.Core
+	}
+	return &levelCore{Core: core, enabler: enabler}
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, check *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return check
+	}
+	return c.Core.Check(entry, check)
+}
+
+// Named returns a child logger, e.g. for a package, whose level can be
+// changed separately with SetLevel.
+func (l *Logger) Named(name string) *Logger {
+	fullName := name
+	if l.name != "" {
+		fullName = l.name + "." + name
+	}
+	enabler := namedLevel{levels: l.levels, name: fullName}
+	zapLogger := l.zapLogger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newLevelCore(core, enabler)
+	}))
+	return &Logger{
+		zapLogger:    zapLogger.Named(name).Sugar(),
+		sentryOption: l.sentryOption,
+		outputOption: l.outputOption,
+		levels:       l.levels,
+		name:         fullName,
+		sentry:       l.sentry,
+	}
+}
+
+// SetLevel changes the level of the logger called name, an empty name
+// changes the level of all loggers without their own level. A positive
+// ttl reverts the change after it elapses.
+func (l *Logger) SetLevel(name, level string, ttl time.Duration) error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	l.levels.set(name, lvl, ttl)
+	return nil
+}
+
+// Levels returns the current levels by logger name, the base level is
+// stored under an empty name.
+func (l *Logger) Levels() map[string]string {
+	return l.levels.all()
+}
+
+// ToggleDebug switches the base level between debug and the configured
+// level, it is bound to SIGUSR1.
+func (l *Logger) ToggleDebug() {
+	lvl := zapcore.DebugLevel
+	if l.levels.level("") == zapcore.DebugLevel {
+		lvl = l.levels.configured
+		if lvl == zapcore.DebugLevel {
+			lvl = zapcore.InfoLevel
+		}
+	}
+	l.levels.set("", lvl, 0)
+	l.Infof("log level changed to %s", lvl)
+}
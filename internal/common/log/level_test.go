@@ -0,0 +1,109 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const ttl = 20 * time.Millisecond
+
+func waitLevel(t *testing.T, ls *levels, name string, want zapcore.Level) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return ls.level(name) == want
+	}, time.Second, time.Millisecond, "level of %q", name)
+}
+
+func TestLevelsTemporary(t *testing.T) {
+	t.Run("reverts base level", func(t *testing.T) {
+		ls := newLevels(zapcore.InfoLevel)
+		ls.set("", zapcore.DebugLevel, ttl)
+		assert.Equal(t, zapcore.DebugLevel, ls.level(""))
+		waitLevel(t, ls, "", zapcore.InfoLevel)
+	})
+
+	t.Run("overlapping changes revert to the persistent level", func(t *testing.T) {
+		ls := newLevels(zapcore.InfoLevel)
+		ls.set("", zapcore.DebugLevel, time.Hour)
+		ls.set("", zapcore.WarnLevel, ttl)
+		assert.Equal(t, zapcore.WarnLevel, ls.level(""))
+		waitLevel(t, ls, "", zapcore.InfoLevel)
+	})
+
+	t.Run("persistent change cancels a temporary one", func(t *testing.T) {
+		ls := newLevels(zapcore.InfoLevel)
+		ls.set("", zapcore.DebugLevel, ttl)
+		ls.set("", zapcore.ErrorLevel, 0)
+		time.Sleep(3 * ttl)
+		assert.Equal(t, zapcore.ErrorLevel, ls.level(""))
+
+		ls.set("", zapcore.DebugLevel, ttl)
+		waitLevel(t, ls, "", zapcore.ErrorLevel)
+	})
+
+	t.Run("named logger inherits the base level again", func(t *testing.T) {
+		ls := newLevels(zapcore.InfoLevel)
+		ls.set("db", zapcore.DebugLevel, ttl)
+		ls.set("db", zapcore.WarnLevel, ttl)
+		assert.Equal(t, zapcore.WarnLevel, ls.level("db"))
+		waitLevel(t, ls, "db", zapcore.InfoLevel)
+		assert.Equal(t, map[string]string{"": "info"}, ls.all())
+
+		ls.set("", zapcore.ErrorLevel, 0)
+		assert.Equal(t, zapcore.ErrorLevel, ls.level("db"))
+	})
+
+	t.Run("named logger restores its persistent level", func(t *testing.T) {
+		ls := newLevels(zapcore.InfoLevel)
+		ls.set("db", zapcore.WarnLevel, 0)
+		ls.set("db", zapcore.DebugLevel, ttl)
+		waitLevel(t, ls, "db", zapcore.WarnLevel)
+		assert.Equal(t, map[string]string{"": "info", "db": "warn"}, ls.all())
+	})
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	l := NewTestLogger()
+	assert.Error(t, l.SetLevel("", "loud", 0))
+
+	require.NoError(t, l.SetLevel("api", "debug", 0))
+	require.NoError(t, l.SetLevel("api.auth", "error", 0))
+	assert.Equal(t, map[string]string{"": "info", "api": "debug", "api.auth": "error"}, l.Levels())
+
+	l.ToggleDebug()
+	assert.Equal(t, zapcore.DebugLevel, l.levels.level(""))
+	l.ToggleDebug()
+	assert.Equal(t, zapcore.InfoLevel, l.levels.level(""))
+}
+
+func TestNamedLevelCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ls := newLevels(zapcore.InfoLevel)
+	l := &Logger{
+		zapLogger: zap.New(newLevelCore(core, namedLevel{levels: ls})).Sugar(),
+		levels:    ls,
+	}
+	api := l.Named("api")
+	auth := api.Named("auth")
+	assert.Equal(t, "api.auth", auth.name)
+
+	require.NoError(t, l.SetLevel("api", "debug", 0))
+	l.Debug("root")
+	api.Debug("api")
+	auth.Debug("auth")
+	require.NoError(t, l.SetLevel("api", "error", 0))
+	api.Warn("api")
+	auth.Warn("auth")
+
+	var messages []string
+	for _, e := range logs.All() {
+		messages = append(messages, e.LoggerName+":"+e.Message)
+	}
+	assert.Equal(t, []string{"api:api", "api.auth:auth"}, messages)
+}
@@ -2,18 +2,44 @@ package log
 
 import (
 	"fmt"
+	"os"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/configx"
 )
 
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
 type Option func(*Logger)
 
 type Logger struct {
 	zapLogger    *zap.SugaredLogger
 	sentryOption sentryOption
+	outputOption outputOption
+	levels       *levels
+	name         string
+	sentry       Sentryer
+}
+
+type outputOption struct {
+	level    string
+	encoding string
+	file     *lumberjack.Logger
+}
+
+// FileOptions configures the log file and its rotation
+type FileOptions struct {
+	Path       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
 }
 
 type sentryOption struct {
@@ -30,41 +56,98 @@ func WithSentry(dsn string, tags map[string]string, fields ...zapcore.Field) Opt
 	}
 }
 
+// WithLevel sets the minimal level: debug, info, warn, error, dpanic,
+// panic or fatal. Defaults to debug in local and development mode and to
+// info otherwise.
+func WithLevel(level string) Option {
+	return func(logger *Logger) {
+		logger.outputOption.level = level
+	}
+}
+
+// WithEncoding sets EncodingJSON or EncodingConsole. Defaults to console in
+// local and development mode and to json otherwise.
+func WithEncoding(encoding string) Option {
+	return func(logger *Logger) {
+		logger.outputOption.encoding = encoding
+	}
+}
+
+// WithFile writes entries to a size rotated file in addition to stderr
+func WithFile(opts FileOptions) Option {
+	return func(logger *Logger) {
+		logger.outputOption.file = &lumberjack.Logger{
+			Filename:   opts.Path,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			Compress:   opts.Compress,
+		}
+	}
+}
+
 // NewTestLogger return instance of Logger that discards all output.
 func NewTestLogger() *Logger {
 	return &Logger{
 		zapLogger: zap.NewNop().Sugar(),
+		levels:    newLevels(zapcore.InfoLevel),
 	}
 }
 
 func New(mode, serviceName string, opts ...Option) (*Logger, error) {
-	zapLogger, err := newZap(mode)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create zap sugared: %w", err)
+	logger := &Logger{}
+	for _, opt := range opts {
+		opt(logger)
 	}
 
-	logger := &Logger{
-		zapLogger: zapLogger.Sugar(),
+	zapOpts := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(1)}
+	if isDevMode(mode) {
+		zapOpts = append(zapOpts, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	} else {
+		zapOpts = append(zapOpts, zap.AddStacktrace(zapcore.ErrorLevel))
 	}
-	for _, opt := range opts {
-		opt(logger)
+
+	core, level, err := newZapCore(mode, logger.outputOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zap sugared: %w", err)
 	}
+	core = markerFilterCore{Core: core}
+	logger.levels = newLevels(level)
+	enabler := namedLevel{levels: logger.levels}
+	logger.zapLogger = zap.New(newLevelCore(core, enabler), zapOpts...).Sugar()
 
 	if logger.sentryOption.sentryDsn == "" || mode != configx.ModeProd {
 		return logger, nil
 	}
 
-	sentryCore, err := newSentryCore(newSentryOptions(logger.sentryOption.sentryDsn, mode, serviceName))
+	sentryOptions := newSentryOptions(logger.sentryOption.sentryDsn, mode, serviceName)
+	for key, value := range logger.sentryOption.sentryTags {
+		sentryOptions.Tags[key] = value
+	}
+	sentryCore, err := newSentryCore(sentryOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init sentry core: %w", err)
 	}
+	sentryCore.fields = append(sentryCore.fields, logger.sentryOption.sentryFields...)
+	logger.sentry = sentryCore.client
 
-	core := zapcore.NewTee(zapLogger.Core(), sentryCore)
-	logger.zapLogger = zap.New(core).Sugar()
+	logger.zapLogger = zap.New(newLevelCore(zapcore.NewTee(core, sentryCore), enabler), zapOpts...).Sugar()
 
 	return logger, nil
 }
 
+// With returns a child logger which adds fields to every entry
+func (l *Logger) With(fields ...Field) *Logger {
+	return &Logger{
+		zapLogger:    l.zapLogger.With(fieldsToInterface(fields)...),
+		sentryOption: l.sentryOption,
+		outputOption: l.outputOption,
+		levels:       l.levels,
+		name:         l.name,
+		sentry:       l.sentry,
+	}
+}
+
 func (l *Logger) Info(msg string, fields ...Field) {
 	l.zapLogger.Infow(msg, fieldsToInterface(fields)...)
 }
@@ -97,12 +180,56 @@ func (l *Logger) Warnf(template string, args ...interface{}) {
 	l.zapLogger.Warnf(template, args...)
 }
 
-func newZap(mode string) (*zap.Logger, error) {
-	opts := []zap.Option{zap.AddCallerSkip(1)}
+// Sentry returns the sentry client, nil unless sentry is enabled
+func (l *Logger) Sentry() Sentryer {
+	return l.sentry
+}
+
+// Sync flushes buffered entries, it must be called before the process exits
+func (l *Logger) Sync() error {
+	return l.zapLogger.Sync()
+}
+
+func isDevMode(mode string) bool {
+	return mode == configx.ModeDev || mode == configx.ModeLocal
+}
+
+// newZapCore returns a core accepting all levels and the configured level,
+// entries are filtered by a levelCore so the level can change at runtime.
+func newZapCore(mode string, out outputOption) (zapcore.Core, zapcore.Level, error) {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	level := zapcore.InfoLevel
+	encoding := EncodingJSON
+	if isDevMode(mode) {
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+		level = zapcore.DebugLevel
+		encoding = EncodingConsole
+	}
+
+	if out.level != "" {
+		if err := level.UnmarshalText([]byte(out.level)); err != nil {
+			return nil, level, fmt.Errorf("invalid log level %q: %w", out.level, err)
+		}
+	}
+	if out.encoding != "" {
+		encoding = out.encoding
+	}
+
+	var encoder zapcore.Encoder
+	switch encoding {
+	case EncodingJSON:
+		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	case EncodingConsole:
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	default:
+		return nil, level, fmt.Errorf("unknown log encoding: %s", encoding)
+	}
 
-	if mode == modeDev {
-		return zap.NewDevelopment(opts...)
+	sinks := []zapcore.WriteSyncer{zapcore.Lock(os.Stderr)}
+	if out.file != nil {
+		sinks = append(sinks, zapcore.AddSync(out.file))
 	}
 
-	return zap.NewProduction(opts...)
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(sinks...), zapcore.DebugLevel)
+	return core, level, nil
 }
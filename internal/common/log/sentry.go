@@ -9,6 +9,8 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/configx"
 )
 
 const (
@@ -19,6 +21,7 @@ const (
 	zapFieldPrefix    = "zapfield_prefix"
 	errorFieldName    = "_error"
 	errorKeyName      = "error"
+	reportedFieldName = "_sentry_reported"
 )
 
 type SentryCore struct {
@@ -49,9 +52,11 @@ func newSentryOptions(dsn, mode, serviceName string) SentryOptions {
 	options := SentryOptions{
 		ClientOptions: sentry.ClientOptions{
 			Dsn:         dsn,
-			Debug:       mode == modeDev,
+			Debug:       mode == configx.ModeDev,
 			Environment: mode,
 		},
+		// lower levels would report every request log line
+		MinLevel:     zapcore.ErrorLevel,
 		FlushTimeout: 0,
 		Tags:         make(map[string]string, 1),
 	}
@@ -197,6 +202,11 @@ func (s *SentryCore) createScope(entry zapcore.Entry, fields []zapcore.Field) (*
 }
 
 func (s *SentryCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if _, ok := findField(reportedFieldName, fields); ok {
+		// the caller has already sent the event with its own scope
+		return nil
+	}
+
 	scope, err := s.createScope(entry, fields)
 	if err != nil {
 		return err
@@ -300,6 +310,46 @@ func fieldValueAsInterface(field zapcore.Field) (interface{}, error) {
 	return nil, fmt.Errorf("unknown field type: %v", field.Type)
 }
 
+// markerFilterCore drops the markers of the sentry core, i.e.
+// SentryReported, from the entries of the core it wraps
+type markerFilterCore struct {
+	zapcore.Core
+}
+
+func (c markerFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return markerFilterCore{Core: c.Core.With(withoutMarkers(fields))}
+}
+
+func (c markerFilterCore) Check(entry zapcore.Entry, check *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return check.AddCore(entry, c)
+	}
+	return check
+}
+
+func (c markerFilterCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, withoutMarkers(fields))
+}
+
+func withoutMarkers(fields []zapcore.Field) []zapcore.Field {
+	if _, ok := findField(reportedFieldName, fields); !ok {
+		return fields
+	}
+	out := make([]zapcore.Field, 0, len(fields)-1)
+	for _, field := range fields {
+		if field.Key != reportedFieldName {
+			out = append(out, field)
+		}
+	}
+	return out
+}
+
 type PanicWrapper struct {
 	error
 }
+
+// NewPanicWrapper marks err as a recovered panic, it is reported to
+// sentry as a fatal event
+func NewPanicWrapper(err error) *PanicWrapper {
+	return &PanicWrapper{err}
+}
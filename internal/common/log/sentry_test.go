@@ -0,0 +1,60 @@
+package log
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// countingSentryer counts the events instead of sending them
+type countingSentryer struct {
+	events int
+}
+
+func (c *countingSentryer) Flush(_ time.Duration) bool {
+	return true
+}
+
+func (c *countingSentryer) Recover(_ interface{}, _ *sentry.EventHint, _ sentry.EventModifier) *sentry.EventID {
+	c.events++
+	return nil
+}
+
+func (c *countingSentryer) CaptureException(_ error, _ *sentry.EventHint, _ sentry.EventModifier) *sentry.EventID {
+	c.events++
+	return nil
+}
+
+func (c *countingSentryer) CaptureMessage(_ string, _ *sentry.EventHint, _ sentry.EventModifier) *sentry.EventID {
+	c.events++
+	return nil
+}
+
+func TestSentryReported(t *testing.T) {
+	output, logs := observer.New(zapcore.DebugLevel)
+	client := &countingSentryer{}
+	sentryCore := &SentryCore{client: client, level: zapcore.ErrorLevel, tags: map[string]string{}}
+	logger := &Logger{
+		zapLogger: zap.New(zapcore.NewTee(markerFilterCore{Core: output}, sentryCore)).Sugar(),
+		levels:    newLevels(zapcore.DebugLevel),
+	}
+
+	logger.Error("panic recovered", Error(errors.New("boom")), SentryReported())
+	assert.Equal(t, 0, client.events, "reported entries are not sent again")
+
+	logger.Error("failed", Error(errors.New("boom")))
+	assert.Equal(t, 1, client.events)
+
+	require.Equal(t, 2, logs.Len())
+	for _, entry := range logs.All() {
+		assert.NotContains(t, entry.ContextMap(), reportedFieldName, "the marker is not written to the log output")
+	}
+	assert.Equal(t, "boom", logs.All()[0].ContextMap()[errorKeyName])
+}
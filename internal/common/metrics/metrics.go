@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes all metrics exported by the service
+const Namespace = "dcd"
+
+const unknownRoute = "unknown"
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests being served.",
+	})
+)
+
+// Handler exposes the default registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records count and latency of requests per echo route and
+// response status. Panics are recorded as 500, the status they are
+// rendered with by the recover middleware.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			httpRequestsInFlight.Inc()
+			defer httpRequestsInFlight.Dec()
+
+			start := time.Now()
+			panicked := true
+			defer func() {
+				route := c.Path()
+				if route == "" {
+					// not matched by the router, do not explode cardinality
+					route = unknownRoute
+				}
+				code := http.StatusInternalServerError
+				if !panicked || c.Response().Committed {
+					code = responseStatus(c, err)
+				}
+				status := strconv.Itoa(code)
+
+				httpRequestsTotal.WithLabelValues(route, c.Request().Method, status).Inc()
+				httpRequestDuration.WithLabelValues(route, c.Request().Method, status).Observe(time.Since(start).Seconds())
+			}()
+
+			err = next(c)
+			panicked = false
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status code which will be sent, the error is
+// rendered by the echo error handler after the middleware returns, with
+// the status of its domain error.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return errorx.HTTPStatus(errorx.FromHTTPStatus(he.Code, ""))
+	}
+	return errorx.HTTPStatus(err)
+}
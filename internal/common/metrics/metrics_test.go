@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "forbidden", err: errorx.ErrForbidden, status: http.StatusForbidden},
+		{name: "too many requests", err: errorx.ErrTooManyRequests, status: http.StatusTooManyRequests},
+		{name: "echo not found", err: echo.ErrNotFound, status: http.StatusNotFound},
+		{name: "echo payload too large", err: echo.ErrStatusRequestEntityTooLarge, status: http.StatusRequestEntityTooLarge},
+		{name: "echo bad gateway", err: echo.ErrBadGateway, status: http.StatusBadGateway},
+		{name: "unknown", err: assert.AnError, status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Middleware())
+			route := "/status/" + strconv.Itoa(tt.status)
+			e.GET(route, func(c echo.Context) error {
+				if tt.err != nil {
+					return tt.err
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			counter := httpRequestsTotal.WithLabelValues(route, http.MethodGet, strconv.Itoa(tt.status))
+			before := testutil.ToFloat64(counter)
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, route, nil))
+			assert.Equal(t, before+1, testutil.ToFloat64(counter))
+		})
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	e := echo.New()
+	e.Use(middleware.Recover())
+	e.Use(Middleware())
+	e.GET("/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+
+	counter := httpRequestsTotal.WithLabelValues("/panic", http.MethodGet, "500")
+	before := testutil.ToFloat64(counter)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst
+// tokens. The zero Limit does not limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited reports whether the limit lets every request through
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return ""
+	}
+	return fmt.Sprintf("%s/s:%d", strconv.FormatFloat(l.Rate, 'f', -1, 64), l.Burst)
+}
+
+// ParseLimit parses "rate/unit[:burst]" with unit s, m, h or d, e.g.
+// "10/s:20" or "600/m". The burst defaults to the rate per unit, rounded
+// up. An empty spec is the unlimited zero Limit.
+func ParseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return Limit{}, nil
+	}
+
+	rateSpec, burstSpec := spec, ""
+	if idx := strings.Index(spec, ":"); idx != -1 {
+		rateSpec, burstSpec = spec[:idx], spec[idx+1:]
+	}
+	parts := strings.Split(rateSpec, "/")
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("%w %q: expecting rate/unit[:burst]", ErrInvalidLimit, spec)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) {
+		return Limit{}, fmt.Errorf("%w %q: rate must be a positive number", ErrInvalidLimit, spec)
+	}
+
+	var per time.Duration
+	switch strings.TrimSpace(parts[1]) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	case "d":
+		per = 24 * time.Hour
+	default:
+		return Limit{}, fmt.Errorf("%w %q: unit must be s, m, h or d", ErrInvalidLimit, spec)
+	}
+
+	burst := int(math.Ceil(n))
+	if burstSpec != "" {
+		if burst, err = strconv.Atoi(strings.TrimSpace(burstSpec)); err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("%w %q: burst must be a positive integer", ErrInvalidLimit, spec)
+		}
+	}
+	return Limit{Rate: n / per.Seconds(), Burst: burst}, nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy holds the limits applied to every client
+type Policy struct {
+	// Default applies to routes without their own limit, all of them share
+	// one bucket per client
+	Default Limit
+	// Routes maps routes, e.g. "GET /api/v1/list", to a limit with a bucket
+	// of its own per client
+	Routes map[string]Limit
+	// DailyQuota is the number of requests a client may make per UTC day,
+	// zero for no quota
+	DailyQuota int64
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	if !p.Default.Unlimited() || p.DailyQuota > 0 {
+		return true
+	}
+	for _, l := range p.Routes {
+		if !l.Unlimited() {
+			return true
+		}
+	}
+	return false
+}
+
+// Decision tells whether a request may proceed
+type Decision struct {
+	Result
+	// Limit is the limit applied, the zero Limit if the route is unlimited
+	Limit Limit
+	// QuotaExceeded is true when the request was denied by the daily quota
+	QuotaExceeded  bool
+	QuotaRemaining int64
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func New(store Store, policy Policy) *Limiter {
+	return &Limiter{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+func (l *Limiter) Policy() Policy {
+	return l.policy
+}
+
+// Allow takes a token from the bucket of client for route and counts the
+// request against the daily quota of client
+func (l *Limiter) Allow(ctx context.Context, client, route string) (Decision, error) {
+	now := l.now()
+	d := Decision{Result: Result{Allowed: true}}
+
+	limit, ok := l.policy.Routes[route]
+	bucketKey := "rl:" + route + ":" + client
+	if !ok {
+		limit = l.policy.Default
+		bucketKey = "rl:*:" + client
+	}
+	if !limit.Unlimited() {
+		res, err := l.store.Take(ctx, bucketKey, limit, now)
+		if err != nil {
+			return d, err
+		}
+		d.Result, d.Limit = res, limit
+		if !res.Allowed {
+			return d, nil
+		}
+	}
+
+	if l.policy.DailyQuota <= 0 {
+		return d, nil
+	}
+	day := now.UTC().Truncate(24 * time.Hour)
+	tomorrow := day.Add(24 * time.Hour)
+	n, err := l.store.Incr(ctx, "quota:"+day.Format("2006-01-02")+":"+client, now, tomorrow)
+	if err != nil {
+		return d, err
+	}
+	d.QuotaRemaining = l.policy.DailyQuota - n
+	if d.QuotaRemaining < 0 {
+		d.QuotaRemaining = 0
+		d.QuotaExceeded = true
+		d.Allowed = false
+		d.RetryAfter = tomorrow.Sub(now)
+	}
+	return d, nil
+}
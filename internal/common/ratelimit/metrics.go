@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ReasonRate  = "rate"
+	ReasonQuota = "quota"
+)
+
+var rejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "ratelimit",
+	Name:      "rejected_total",
+	Help:      "Number of requests rejected by rate limits and quotas.",
+}, []string{"route", "reason"})
+
+// ObserveRejected counts a rejected request
+func ObserveRejected(route string, d Decision) {
+	reason := ReasonRate
+	if d.QuotaExceeded {
+		reason = ReasonQuota
+	}
+	rejectedTotal.WithLabelValues(route, reason).Inc()
+}
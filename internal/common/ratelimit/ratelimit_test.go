@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testParseLimit struct {
+	spec  string
+	limit Limit
+	err   bool
+}
+
+func TestParseLimit(t *testing.T) {
+	testCases := []testParseLimit{
+		{spec: "", limit: Limit{}},
+		{spec: "10/s", limit: Limit{Rate: 10, Burst: 10}},
+		{spec: "10/s:20", limit: Limit{Rate: 10, Burst: 20}},
+		{spec: "120/m", limit: Limit{Rate: 2, Burst: 120}},
+		{spec: "0.5/s", limit: Limit{Rate: 0.5, Burst: 1}},
+		{spec: "10", err: true},
+		{spec: "10/w", err: true},
+		{spec: "-1/s", err: true},
+		{spec: "10/s:0", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			limit, err := ParseLimit(tc.spec)
+			if tc.err {
+				assert.True(t, errors.Is(err, ErrInvalidLimit))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.limit, limit)
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 23, 59, 0, 0, time.UTC)
+	l := New(NewMemoryStore(), Policy{
+		Default: Limit{Rate: 1, Burst: 2},
+		Routes:  map[string]Limit{"GET /api/v1/list": {Rate: 0.5, Burst: 1}},
+	})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		d, err := l.Allow(ctx, "ip:1.2.3.4", "POST /api/v1/couriers/:id/location")
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 1-i, d.Remaining)
+	}
+	d, err := l.Allow(ctx, "ip:1.2.3.4", "POST /api/v1/couriers/:id/location")
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 2*time.Second, d.Reset)
+
+	// the route has a bucket of its own, other clients are not limited
+	d, _ = l.Allow(ctx, "ip:1.2.3.4", "GET /api/v1/list")
+	assert.True(t, d.Allowed)
+	d, _ = l.Allow(ctx, "ip:1.2.3.4", "GET /api/v1/list")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 2*time.Second, d.RetryAfter)
+	d, _ = l.Allow(ctx, "api_key:backoffice", "GET /api/v1/list")
+	assert.True(t, d.Allowed)
+
+	now = now.Add(time.Second)
+	d, _ = l.Allow(ctx, "ip:1.2.3.4", "POST /api/v1/couriers/:id/location")
+	assert.True(t, d.Allowed)
+}
+
+func TestDailyQuota(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 23, 59, 0, 0, time.UTC)
+	l := New(NewMemoryStore(), Policy{DailyQuota: 2})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		d, err := l.Allow(ctx, "jwt:42", "GET /api/v1/list")
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, int64(1-i), d.QuotaRemaining)
+	}
+	d, _ := l.Allow(ctx, "jwt:42", "GET /api/v1/list")
+	assert.False(t, d.Allowed)
+	assert.True(t, d.QuotaExceeded)
+	assert.Equal(t, time.Minute, d.RetryAfter)
+
+	// quotas are reset at midnight UTC
+	now = now.Add(time.Minute)
+	d, _ = l.Allow(ctx, "jwt:42", "GET /api/v1/list")
+	assert.True(t, d.Allowed)
+}
@@ -0,0 +1,204 @@
+package tlsx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client authentication modes
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+var (
+	ErrNoCertificate     = errors.New("certificate and key files are required")
+	ErrUnknownClientAuth = errors.New("unknown client auth mode")
+	ErrNoClientCA        = errors.New("client CA file is required for client auth")
+	ErrInvalidClientCA   = errors.New("no certificate in client CA file")
+	ErrCertExpired       = errors.New("certificate expired")
+)
+
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the CAs verifying client certificates
+	ClientCAFile string
+	// ClientAuth is ClientAuthNone, ClientAuthOptional or ClientAuthRequire,
+	// empty for none
+	ClientAuth string
+}
+
+// Validate checks the configuration without reading the files
+func (c Config) Validate() error {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return ErrNoCertificate
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthNone:
+		return nil
+	case ClientAuthOptional, ClientAuthRequire:
+		if c.ClientCAFile == "" {
+			return ErrNoClientCA
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownClientAuth, c.ClientAuth)
+	}
+}
+
+// Reloader serves a certificate and client CAs which can be replaced while
+// the server is running, so renewed certificates are picked up without
+// dropping connections.
+type Reloader struct {
+	cfg        Config
+	clientAuth tls.ClientAuthType
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	leaf      *x509.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+func NewReloader(cfg Config) (*Reloader, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Reloader{cfg: cfg, clientAuth: tls.NoClientCert}
+	switch cfg.ClientAuth {
+	case ClientAuthOptional:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	}
+	if err := r.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. The current certificate is kept if they
+// are invalid.
+func (r *Reloader) Reload(_ context.Context) error {
+	modTimes := make(map[string]time.Time)
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%w: %s", ErrInvalidClientCA, r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.leaf, r.clientCAs, r.modTimes = &cert, leaf, pool, modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the files whenever one of them is modified, checking every
+// interval until ctx is done. Errors are passed to onError and the current
+// certificate is kept.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if !r.modified() {
+				continue
+			}
+			if err := r.Reload(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// TLSConfig returns a server config using the current certificate and
+// client CAs for every handshake. It sets GetCertificate too, so
+// http.Server.ListenAndServeTLS("", "") takes the certificate from it.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// GetCertificate returns the current certificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Check fails once the certificate has expired
+func (r *Reloader) Check(_ context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if time.Now().After(r.leaf.NotAfter) {
+		return fmt.Errorf("%w on %s", ErrCertExpired, r.leaf.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) modified() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
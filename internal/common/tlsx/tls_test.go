@@ -0,0 +1,210 @@
+package tlsx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue creates a certificate signed by parent, self-signed if nil
+func issue(t *testing.T, cn string, notAfter time.Time, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	if keyFile == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{name: "no key", cfg: Config{CertFile: "c.pem"}, err: ErrNoCertificate},
+		{name: "server only", cfg: Config{CertFile: "c.pem", KeyFile: "k.pem"}},
+		{name: "client auth without CA", cfg: Config{CertFile: "c.pem", KeyFile: "k.pem", ClientAuth: ClientAuthRequire}, err: ErrNoClientCA},
+		{name: "unknown client auth", cfg: Config{CertFile: "c.pem", KeyFile: "k.pem", ClientAuth: "always"}, err: ErrUnknownClientAuth},
+		{name: "mtls", cfg: Config{CertFile: "c.pem", KeyFile: "k.pem", ClientCAFile: "ca.pem", ClientAuth: ClientAuthOptional}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	first := issue(t, "first.local", time.Now().Add(time.Hour), nil)
+	first.write(t, cfg.CertFile, cfg.KeyFile)
+
+	r, err := NewReloader(cfg)
+	require.NoError(t, err)
+	assert.NoError(t, r.Check(context.Background()))
+	assert.Equal(t, "first.local", r.leaf.Subject.CommonName)
+	assert.False(t, r.modified())
+
+	// a broken file keeps the current certificate
+	require.NoError(t, os.WriteFile(cfg.CertFile, []byte("garbage"), 0o600))
+	assert.Error(t, r.Reload(context.Background()))
+	assert.Equal(t, "first.local", r.leaf.Subject.CommonName)
+
+	expired := issue(t, "second.local", time.Now().Add(-time.Minute), nil)
+	expired.write(t, cfg.CertFile, cfg.KeyFile)
+	require.NoError(t, r.Reload(context.Background()))
+	assert.Equal(t, "second.local", r.leaf.Subject.CommonName)
+	assert.ErrorIs(t, r.Check(context.Background()), ErrCertExpired)
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "ca", time.Now().Add(time.Hour), nil)
+	cfg := Config{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+		ClientAuth:   ClientAuthRequire,
+	}
+	issue(t, "localhost", time.Now().Add(time.Hour), ca).write(t, cfg.CertFile, cfg.KeyFile)
+	ca.write(t, cfg.ClientCAFile, "")
+
+	r, err := NewReloader(cfg)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(req.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+			MinVersion:   tls.VersionTLS12,
+		}}}
+	}
+
+	res, err := client(issue(t, "billing", time.Now().Add(time.Hour), ca).tls()).Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	_, err = client().Get(srv.URL)
+	assert.Error(t, err, "client without certificate")
+
+	_, err = client(issue(t, "billing", time.Now().Add(time.Hour), nil).tls()).Get(srv.URL)
+	assert.Error(t, err, "client certificate of an unknown CA")
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "ca", time.Now().Add(time.Hour), nil)
+	cfg := Config{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	issue(t, "localhost", time.Now().Add(time.Hour), ca).write(t, cfg.CertFile, cfg.KeyFile)
+
+	r, err := NewReloader(cfg)
+	require.NoError(t, err)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler:   http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		TLSConfig: r.TLSConfig(),
+	}
+	done := make(chan error, 1)
+	go func() {
+		// the certificate is provided by the TLS config
+		done <- srv.ServeTLS(ln, "", "")
+	}()
+	defer func() {
+		require.NoError(t, srv.Close())
+		assert.ErrorIs(t, <-done, http.ErrServerClosed)
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	served := func() string {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    roots,
+			ServerName: "localhost",
+			MinVersion: tls.VersionTLS12,
+		}}}
+		res, err := client.Get("https://" + ln.Addr().String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "localhost", served())
+
+	renewed := issue(t, "localhost", time.Now().Add(2*time.Hour), ca)
+	renewed.write(t, cfg.CertFile, cfg.KeyFile)
+	require.NoError(t, r.Reload(context.Background()))
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, renewed.cert.Raw, cert.Certificate[0])
+	assert.Equal(t, "localhost", served())
+}
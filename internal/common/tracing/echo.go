@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const TraceIDHeader = "X-Trace-Id"
+
+// EchoTrace returns a Trace middleware for echo.
+func EchoTrace(tracer opentracing.Tracer) echo.MiddlewareFunc {
+	c := DefaultTraceConfig
+	c.Tracer = tracer
+	return EchoTraceWithConfig(c)
+}
+
+// EchoTraceWithConfig returns a Trace middleware for echo with config.
+// The span is stored in the request context, so spans created by
+// `CreateSpan` in handlers become its children.
+func EchoTraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
+	if config.Tracer == nil {
+		panic("trace middleware requires opentracing tracer")
+	}
+	if config.ComponentName == "" {
+		config.ComponentName = defaultComponentName
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			route := c.Path()
+			if route == "" {
+				route = r.URL.Path
+			}
+			operationName := "HTTP " + r.Method + " URL: " + route
+
+			var sp opentracing.Span
+			tr := config.Tracer
+			if ctx, err := tr.Extract(opentracing.HTTPHeaders,
+				opentracing.HTTPHeadersCarrier(r.Header)); err != nil {
+				sp = tr.StartSpan(operationName)
+			} else {
+				sp = tr.StartSpan(operationName, ext.RPCServerOption(ctx))
+			}
+
+			ext.HTTPMethod.Set(sp, r.Method)
+			ext.HTTPUrl.Set(sp, r.URL.String())
+			ext.Component.Set(sp, config.ComponentName)
+
+			// Dump request & response
+			resBody := new(bytes.Buffer)
+			if config.IsBodyDump {
+				var reqBody []byte
+				if r.Body != nil {
+					reqBody, _ = ioutil.ReadAll(r.Body)
+					sp.SetTag("http.req.body", string(reqBody))
+				}
+				r.Body = ioutil.NopCloser(bytes.NewBuffer(reqBody)) // Reset
+
+				w := c.Response().Writer
+				c.Response().Writer = &responseWriter{Writer: io.MultiWriter(w, resBody), ResponseWriter: w}
+			}
+
+			ctx := opentracing.ContextWithSpan(r.Context(), sp)
+			if traceID := TraceID(ctx); traceID != "" {
+				c.Response().Header().Set(TraceIDHeader, traceID)
+			}
+			c.SetRequest(r.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				// let the error handler render the response before the span
+				// is finished so the status code is known, it is rendered
+				// once so the error is not returned
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			ext.HTTPStatusCode.Set(sp, uint16(status))
+			if status >= http.StatusInternalServerError {
+				ext.Error.Set(sp, true)
+			}
+			if config.IsBodyDump {
+				sp.SetTag("http.resp.body", resBody.String())
+			}
+			sp.Finish()
+
+			return nil
+		}
+	}
+}
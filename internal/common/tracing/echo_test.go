@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEchoTraceError(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	var rendered int
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		rendered++
+		e.DefaultHTTPErrorHandler(err, c)
+	}
+	e.Use(EchoTrace(tracer))
+	e.GET("/couriers/:id", func(c echo.Context) error {
+		return echo.ErrNotFound
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/couriers/42", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, 1, rendered, "the error is rendered once")
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "HTTP GET URL: /couriers/:id", spans[0].OperationName)
+	assert.Equal(t, uint16(http.StatusNotFound), spans[0].Tag("http.status_code"))
+	assert.Nil(t, spans[0].Tag("error"))
+	assert.NotEmpty(t, rec.Header().Get(TraceIDHeader))
+}
@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -10,6 +11,10 @@ import (
 	"github.com/opentracing/opentracing-go/log"
 )
 
+var (
+	ErrTracerNotRegistered = errors.New("global tracer is not registered")
+)
+
 // Span is copy of opentracing.Span interface. It helps us to
 // wrap the opentracing.Span and expose the local span to other
 // layers.
@@ -68,10 +73,20 @@ func LogSpanError(span Span, message string, err error) {
 }
 
 // InjectHTTPCarrier extracts trace ID from span and injects it
-// into the http header.
+// into the http header. With an OpenTelemetry exporter the W3C
+// traceparent header is used.
 func InjectHTTPCarrier(span Span, req *http.Request) error {
 	return opentracing.GlobalTracer().Inject(
 		span.Context(),
 		opentracing.HTTPHeaders,
 		opentracing.HTTPHeadersCarrier(req.Header))
 }
+
+// Check is the health check of the tracing exporter installed by Init. It
+// fails while tracing is disabled and when the last export failed.
+func Check(_ context.Context) error {
+	if !opentracing.IsGlobalTracerRegistered() {
+		return ErrTracerNotRegistered
+	}
+	return status.check()
+}
@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
+
+	samplerConst         = "const"
+	samplerProbabilistic = "probabilistic"
+
+	otelShutdownTimeout = 5 * time.Second
+)
+
+// newOTelTracer creates an OpenTelemetry tracer provider and returns the
+// OpenTracing bridge to it, so spans created through the opentracing API
+// (CreateSpan, the middlewares) are exported by OpenTelemetry. The provider
+// is registered globally too, for code already migrated to the otel API.
+func newOTelTracer(cfg Config) (opentracing.Tracer, io.Closer, error) {
+	exporter, closeExporter, err := newOTelExporter(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(&statusExporter{SpanExporter: exporter, status: status}),
+		sdktrace.WithSampler(otelSampler(cfg)),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	)
+
+	// W3C traceparent and baggage headers
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	bridgeTracer, wrapperProvider := otbridge.NewTracerPair(provider.Tracer(instrumentationName))
+	bridgeTracer.SetTextMapPropagator(propagator)
+
+	otel.SetTextMapPropagator(propagator)
+	otel.SetTracerProvider(wrapperProvider)
+
+	closer := closerFunc(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), otelShutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			return fmt.Errorf("failed to shutdown tracer provider: %w", err)
+		}
+		return closeExporter()
+	})
+	return bridgeTracer, closer, nil
+}
+
+// newOTelExporter returns the span exporter for cfg.Exporter:
+// ExporterOTLP sends spans over OTLP/HTTP to cfg.Endpoint, ExporterStdout
+// and ExporterFile write them as JSON for offline use.
+func newOTelExporter(cfg Config) (sdktrace.SpanExporter, func() error, error) {
+	noop := func() error { return nil }
+
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(context.Background(), otlpOptions(cfg.Endpoint)...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not initialize otlp exporter: %w", err)
+		}
+		return exporter, noop, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not initialize stdout exporter: %w", err)
+		}
+		return exporter, noop, nil
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.Endpoint, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, fmt.Errorf("could not initialize file exporter: %w", err)
+		}
+		return exporter, f.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedExporter, cfg.Exporter)
+	}
+}
+
+// otlpOptions accepts either host:port, which is sent over TLS, or a
+// http(s):// URL.
+func otlpOptions(endpoint string) []otlptracehttp.Option {
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	return opts
+}
+
+// otelSampler maps the Jaeger sampler settings, remote sampling is not
+// supported and samples everything.
+func otelSampler(cfg Config) sdktrace.Sampler {
+	switch cfg.SamplerType {
+	case samplerConst:
+		if cfg.SamplerParam < 1 {
+			return sdktrace.ParentBased(sdktrace.NeverSample())
+		}
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case samplerProbabilistic:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplerParam))
+	default:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+}
+
+// otelTraceID returns the trace ID of the OpenTelemetry span in ctx, the
+// bridge stores it there when the opentracing span is put into the context.
+func otelTraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// statusExporter records the result of every export for Check
+type statusExporter struct {
+	sdktrace.SpanExporter
+	status *exportStatus
+}
+
+func (e *statusExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.status.exported(err)
+	return err
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewTracer(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected interface{}
+	}{
+		{name: "default", cfg: Config{}, expected: opentracing.NoopTracer{}},
+		{name: "noop", cfg: Config{Exporter: ExporterNoop}, expected: opentracing.NoopTracer{}},
+		{name: "memory", cfg: Config{Exporter: ExporterMemory}, expected: &mocktracer.MockTracer{}},
+		{name: "otlp", cfg: Config{Exporter: ExporterOTLP, Endpoint: "http://127.0.0.1:4318"}, expected: &otbridge.BridgeTracer{}},
+		{name: "stdout", cfg: Config{Exporter: ExporterStdout}, expected: &otbridge.BridgeTracer{}},
+		{name: "file", cfg: Config{Exporter: ExporterFile}, expected: &otbridge.BridgeTracer{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.Exporter == ExporterFile {
+				tt.cfg.Endpoint = filepath.Join(t.TempDir(), "traces.json")
+			}
+			tracer, closer, err := newTracer(tt.cfg)
+			require.NoError(t, err)
+			assert.IsType(t, tt.expected, tracer)
+			assert.NoError(t, closer.Close())
+		})
+	}
+
+	_, _, err := newTracer(Config{Exporter: "zipkin"})
+	assert.ErrorIs(t, err, ErrUnsupportedExporter)
+	_, _, err = newTracer(Config{Exporter: ExporterFile, Endpoint: filepath.Join(t.TempDir(), "missing", "traces.json")})
+	assert.Error(t, err)
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	tracer, closer, err := newTracer(Config{Exporter: ExporterFile, Endpoint: path, ServiceName: "dcd-test"})
+	require.NoError(t, err)
+
+	sp := tracer.StartSpan("assign courier")
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+	traceID := TraceID(ctx)
+	assert.Len(t, traceID, 32, "the W3C trace ID of the span")
+	sp.Finish()
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"assign courier"`)
+	assert.Contains(t, string(data), traceID)
+	assert.Contains(t, string(data), "dcd-test")
+}
+
+func TestW3CPropagation(t *testing.T) {
+	tracer, closer, err := newTracer(Config{Exporter: ExporterFile, Endpoint: filepath.Join(t.TempDir(), "traces.json")})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	t.Run("extract", func(t *testing.T) {
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		header := http.Header{}
+		header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		parent, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+		require.NoError(t, err)
+
+		sp := tracer.StartSpan("child", opentracing.ChildOf(parent))
+		defer sp.Finish()
+		ctx := opentracing.ContextWithSpan(context.Background(), sp)
+		assert.Equal(t, traceID, TraceID(ctx))
+	})
+
+	t.Run("inject", func(t *testing.T) {
+		sp := tracer.StartSpan("outgoing")
+		defer sp.Finish()
+		sp.SetBaggageItem("tenant", "tehran")
+		ctx := opentracing.ContextWithSpan(context.Background(), sp)
+
+		header := http.Header{}
+		require.NoError(t, tracer.Inject(sp.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)))
+		assert.Regexp(t, `^00-`+TraceID(ctx)+`-[0-9a-f]{16}-01$`, header.Get("traceparent"))
+		assert.Regexp(t, `(?i)tenant=tehran`, header.Get("baggage"), "baggage keys are canonicalised by the bridge")
+	})
+}
+
+func TestCheck(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	initTracer := func(t *testing.T, cfg Config) (opentracing.Tracer, func()) {
+		t.Helper()
+		tracer, closer, err := Init(cfg)
+		require.NoError(t, err)
+		return tracer, func() { require.NoError(t, closer.Close()) }
+	}
+
+	t.Run("noop", func(t *testing.T) {
+		_, closeTracer := initTracer(t, Config{Exporter: ExporterNoop})
+		defer closeTracer()
+		assert.ErrorIs(t, Check(context.Background()), ErrTracingDisabled)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		_, closeTracer := initTracer(t, Config{Exporter: ExporterMemory})
+		defer closeTracer()
+		assert.NoError(t, Check(context.Background()))
+	})
+
+	t.Run("exported", func(t *testing.T) {
+		tracer, closeTracer := initTracer(t, Config{Exporter: ExporterFile, Endpoint: filepath.Join(t.TempDir(), "traces.json")})
+		tracer.StartSpan("exported").Finish()
+		closeTracer()
+		assert.NoError(t, Check(context.Background()))
+	})
+
+	t.Run("export failed", func(t *testing.T) {
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			// not retried by the exporter
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer collector.Close()
+
+		tracer, closeTracer := initTracer(t, Config{Exporter: ExporterOTLP, Endpoint: collector.URL})
+		assert.NoError(t, Check(context.Background()), "healthy until spans are exported")
+		tracer.StartSpan("rejected").Finish()
+		closeTracer()
+		assert.ErrorContains(t, Check(context.Background()), "failed to export spans")
+	})
+}
+
+func TestOTelSampler(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		sampled bool
+	}{
+		{name: "const on", cfg: Config{SamplerType: samplerConst, SamplerParam: 1}, sampled: true},
+		{name: "const off", cfg: Config{SamplerType: samplerConst, SamplerParam: 0}},
+		{name: "probabilistic all", cfg: Config{SamplerType: samplerProbabilistic, SamplerParam: 1}, sampled: true},
+		{name: "probabilistic none", cfg: Config{SamplerType: samplerProbabilistic, SamplerParam: 0}},
+		{name: "remote", cfg: Config{SamplerType: "remote"}, sampled: true},
+	}
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := otelSampler(tt.cfg).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: context.Background(),
+				TraceID:       traceID,
+				Name:          "root",
+			})
+			assert.Equal(t, tt.sampled, res.Decision == sdktrace.RecordAndSample)
+		})
+	}
+}
+
+func TestOTLPOptions(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		options  int
+	}{
+		{endpoint: ""},
+		{endpoint: "collector:4318", options: 1},
+		{endpoint: "https://collector:4318", options: 1},
+		{endpoint: "http://collector:4318", options: 2},
+		{endpoint: "http://collector:4318/custom/v1/traces", options: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			assert.Len(t, otlpOptions(tt.endpoint), tt.options)
+		})
+	}
+}
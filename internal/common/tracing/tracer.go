@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+const (
+	ExporterNoop   = "noop"
+	ExporterMemory = "memory"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+
+	defaultServiceName = "http-tracer"
+)
+
+var (
+	ErrUnsupportedExporter = errors.New("unsupported tracing exporter")
+	ErrTracingDisabled     = errors.New("tracing is disabled")
+)
+
+// Config defines how the tracer is created by Init.
+type Config struct {
+	ServiceName string
+
+	// Exporter is one of ExporterNoop, ExporterMemory, ExporterOTLP,
+	// ExporterStdout or ExporterFile
+	Exporter string
+
+	// Endpoint is the OTLP/HTTP collector host:port or URL, or the path
+	// of the trace file for ExporterFile
+	Endpoint string
+
+	// SamplerType is const or probabilistic, the Jaeger types ratelimiting
+	// and remote are accepted and sample everything
+	SamplerType  string
+	SamplerParam float64
+}
+
+// Init creates the tracer described by cfg and installs it as the global
+// tracer. The returned Closer flushes buffered spans and must be closed
+// on shutdown.
+// Error ErrUnsupportedExporter
+func Init(cfg Config) (opentracing.Tracer, io.Closer, error) {
+	status.reset(cfg.Exporter)
+	tracer, closer, err := newTracer(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	opentracing.SetGlobalTracer(tracer)
+	return tracer, closer, nil
+}
+
+func newTracer(cfg Config) (opentracing.Tracer, io.Closer, error) {
+	switch cfg.Exporter {
+	case ExporterNoop, "":
+		return opentracing.NoopTracer{}, ioutil.NopCloser(nil), nil
+	case ExporterMemory:
+		return mocktracer.New(), ioutil.NopCloser(nil), nil
+	case ExporterOTLP, ExporterStdout, ExporterFile:
+		return newOTelTracer(cfg)
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedExporter, cfg.Exporter)
+	}
+}
+
+// TraceID returns the trace ID of the span stored in ctx, or an empty
+// string if there is none.
+func TraceID(ctx context.Context) string {
+	if traceID := otelTraceID(ctx); traceID != "" {
+		return traceID
+	}
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return ""
+	}
+	return spanTraceID(sp.Context())
+}
+
+func spanTraceID(sc opentracing.SpanContext) string {
+	switch c := sc.(type) {
+	case mocktracer.MockSpanContext:
+		return strconv.Itoa(c.TraceID)
+	default:
+		return ""
+	}
+}
+
+// exportStatus is the state of the exporter installed by Init, it is
+// reported by Check
+type exportStatus struct {
+	mu       sync.Mutex
+	exporter string
+	// err is the result of the last export, nil until spans are exported
+	err error
+}
+
+var status = &exportStatus{}
+
+func (s *exportStatus) reset(exporter string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exporter, s.err = exporter, nil
+}
+
+func (s *exportStatus) exported(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *exportStatus) check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.exporter == "" || s.exporter == ExporterNoop:
+		return ErrTracingDisabled
+	case s.err != nil:
+		return fmt.Errorf("failed to export spans: %w", s.err)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,215 @@
+package validation
+
+import (
+	"math"
+)
+
+const (
+	// MaxPoints is the default limit of points accepted in one request
+	MaxPoints = 1000
+
+	// nullIslandEpsilon is about 1cm, coordinates closer to 0,0 are almost
+	// always a client sending zero values instead of a location
+	nullIslandEpsilon = 1e-7
+)
+
+// Point is a WGS84 coordinate in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Polygon is a ring of points, it may or may not repeat the first point at
+// the end
+type Polygon []Point
+
+// BoundingBox is a rectangle of coordinates. Boxes crossing the antimeridian
+// have MinLng greater than MaxLng.
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+func IsLatitudeValid(lat float64) bool {
+	return isFinite(lat) && lat >= -90 && lat <= 90
+}
+
+func IsLongitudeValid(lng float64) bool {
+	return isFinite(lng) && lng >= -180 && lng <= 180
+}
+
+// IsNullIsland returns true for coordinates at 0,0
+func IsNullIsland(lat, lng float64) bool {
+	return math.Abs(lat) < nullIslandEpsilon && math.Abs(lng) < nullIslandEpsilon
+}
+
+func IsPointValid(p Point) bool {
+	return IsLatitudeValid(p.Lat) && IsLongitudeValid(p.Lng) && !IsNullIsland(p.Lat, p.Lng)
+}
+
+// IsPolygonValid returns true for rings of at least 3 valid points with a
+// non-zero area which do not intersect themselves
+func IsPolygonValid(points []Point) bool {
+	ring := openRing(points)
+	if len(ring) < 3 {
+		return false
+	}
+	for _, p := range ring {
+		if !IsPointValid(p) {
+			return false
+		}
+	}
+	return signedArea(ring) != 0 && !IsPolygonSelfIntersecting(ring)
+}
+
+// IsPolygonSelfIntersecting returns true if two non-adjacent edges of the
+// ring touch or cross. Coordinates are treated as planar, which is accurate
+// enough for delivery zones.
+func IsPolygonSelfIntersecting(points []Point) bool {
+	ring := openRing(points)
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		a1, a2 := ring[i], ring[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			// adjacent edges share a vertex
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			if segmentsIntersect(a1, a2, ring[j], ring[(j+1)%n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsBoundingBoxValid returns true if all corners are valid coordinates, the
+// south edge is not above the north edge and the box is not empty
+func IsBoundingBoxValid(b BoundingBox) bool {
+	if !IsLatitudeValid(b.MinLat) || !IsLatitudeValid(b.MaxLat) ||
+		!IsLongitudeValid(b.MinLng) || !IsLongitudeValid(b.MaxLng) {
+		return false
+	}
+	return b.MinLat < b.MaxLat && b.MinLng != b.MaxLng
+}
+
+func (p *Point) Validate() *Result {
+	return ValidatePoint("", *p)
+}
+
+func (p *Polygon) Validate() *Result {
+	return ValidatePolygon("", *p, MaxPoints)
+}
+
+func (b *BoundingBox) Validate() *Result {
+	return ValidateBoundingBox("", *b)
+}
+
+// ValidatePoint validates a coordinate, errors are reported on
+// field.lat and field.lng, or on lat and lng for an empty field
+func ValidatePoint(field string, p Point) *Result {
+	out := NewResult()
+	if !IsLatitudeValid(p.Lat) {
+		out.AddFieldError(fieldPath(field, "lat"), InvalidLatitude())
+	}
+	if !IsLongitudeValid(p.Lng) {
+		out.AddFieldError(fieldPath(field, "lng"), InvalidLongitude())
+	}
+	if out.IsValid() && IsNullIsland(p.Lat, p.Lng) {
+		out.AddFieldError(fieldOrDefault(field, "location"), NullIsland())
+	}
+	return out
+}
+
+// ValidatePoints validates every point and the number of points
+func ValidatePoints(field string, points []Point, max int) *Result {
+	field = fieldOrDefault(field, "points")
+	out := NewResult()
+	if len(points) > max {
+		return out.AddFieldError(field, TooManyPoints(max))
+	}
+	for i, p := range points {
+		out.AddResult(ValidatePoint(indexPath(field, i), p))
+	}
+	return out
+}
+
+// ValidatePolygon validates the points and the shape of a polygon
+func ValidatePolygon(field string, points []Point, max int) *Result {
+	field = fieldOrDefault(field, "polygon")
+	out := ValidatePoints(field, points, max)
+	if !out.IsValid() {
+		return out
+	}
+	ring := openRing(points)
+	switch {
+	case len(ring) < 3:
+		out.AddFieldError(field, InvalidPolygon())
+	case IsPolygonSelfIntersecting(ring):
+		out.AddFieldError(field, SelfIntersectingPolygon())
+	case signedArea(ring) == 0:
+		out.AddFieldError(field, InvalidPolygon())
+	}
+	return out
+}
+
+func ValidateBoundingBox(field string, b BoundingBox) *Result {
+	out := NewResult()
+	if !IsBoundingBoxValid(b) {
+		out.AddFieldError(fieldOrDefault(field, "bbox"), InvalidBoundingBox())
+	}
+	return out
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// openRing drops the closing point of a ring
+func openRing(points []Point) []Point {
+	if n := len(points); n > 1 && points[0] == points[n-1] {
+		return points[:n-1]
+	}
+	return points
+}
+
+func signedArea(ring []Point) float64 {
+	var area float64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		area += a.Lng*b.Lat - b.Lng*a.Lat
+	}
+	return area / 2
+}
+
+func orientation(a, b, c Point) int {
+	v := (b.Lng-a.Lng)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lng-a.Lng)
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// onSegment returns true if c, collinear with a and b, lies between them
+func onSegment(a, b, c Point) bool {
+	return math.Min(a.Lng, b.Lng) <= c.Lng && c.Lng <= math.Max(a.Lng, b.Lng) &&
+		math.Min(a.Lat, b.Lat) <= c.Lat && c.Lat <= math.Max(a.Lat, b.Lat)
+}
+
+func segmentsIntersect(p1, p2, q1, q2 Point) bool {
+	o1, o2 := orientation(p1, p2, q1), orientation(p1, p2, q2)
+	o3, o4 := orientation(q1, q2, p1), orientation(q1, q2, p2)
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+	return (o1 == 0 && onSegment(p1, p2, q1)) ||
+		(o2 == 0 && onSegment(p1, p2, q2)) ||
+		(o3 == 0 && onSegment(q1, q2, p1)) ||
+		(o4 == 0 && onSegment(q1, q2, p2))
+}
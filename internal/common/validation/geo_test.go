@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testIsPointValid struct {
+	p     Point
+	valid bool
+}
+
+type testIsPolygonValid struct {
+	name             string
+	points           []Point
+	valid            bool
+	selfIntersecting bool
+}
+
+func TestIsPointValid(t *testing.T) {
+	testCases := []testIsPointValid{
+		{p: Point{Lat: 35.6892, Lng: 51.3890}, valid: true},
+		{p: Point{Lat: -90, Lng: 180}, valid: true},
+		{p: Point{Lat: 0, Lng: 12.5}, valid: true},
+		{p: Point{Lat: 90.0001, Lng: 0.5}, valid: false},
+		{p: Point{Lat: 10, Lng: -180.5}, valid: false},
+		{p: Point{Lat: math.NaN(), Lng: 10}, valid: false},
+		{p: Point{Lat: 10, Lng: math.Inf(1)}, valid: false},
+		{p: Point{Lat: 0, Lng: 0}, valid: false},        // null island
+		{p: Point{Lat: 1e-9, Lng: -1e-9}, valid: false}, // null island
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v,%v", tc.p.Lat, tc.p.Lng), func(t *testing.T) {
+			assert.Equal(t, tc.valid, IsPointValid(tc.p))
+		})
+	}
+}
+
+func TestIsPolygonValid(t *testing.T) {
+	testCases := []testIsPolygonValid{
+		{name: "square", points: []Point{{1, 1}, {1, 2}, {2, 2}, {2, 1}}, valid: true},
+		{name: "closed square", points: []Point{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}, valid: true},
+		{name: "concave", points: []Point{{1, 1}, {1, 3}, {2, 2}, {3, 3}, {3, 1}}, valid: true},
+		{name: "two points", points: []Point{{1, 1}, {1, 2}}, valid: false},
+		{name: "collinear", points: []Point{{1, 1}, {1, 2}, {1, 3}}, valid: false},
+		{name: "bowtie", points: []Point{{1, 1}, {2, 2}, {1, 2}, {2, 1}}, valid: false, selfIntersecting: true},
+		{name: "touching", points: []Point{{1, 1}, {1, 3}, {2, 2}, {1, 2}, {0, 2}}, valid: false, selfIntersecting: true},
+		{name: "invalid point", points: []Point{{1, 1}, {1, 2}, {95, 2}}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, IsPolygonValid(tc.points))
+			assert.Equal(t, tc.selfIntersecting, IsPolygonSelfIntersecting(tc.points))
+		})
+	}
+}
+
+func TestIsBoundingBoxValid(t *testing.T) {
+	assert.True(t, IsBoundingBoxValid(BoundingBox{MinLat: 35, MinLng: 51, MaxLat: 36, MaxLng: 52}))
+	assert.True(t, IsBoundingBoxValid(BoundingBox{MinLat: -10, MinLng: 170, MaxLat: 10, MaxLng: -170})) // antimeridian
+	assert.False(t, IsBoundingBoxValid(BoundingBox{MinLat: 36, MinLng: 51, MaxLat: 35, MaxLng: 52}))
+	assert.False(t, IsBoundingBoxValid(BoundingBox{MinLat: 35, MinLng: 51, MaxLat: 36, MaxLng: 51}))
+	assert.False(t, IsBoundingBoxValid(BoundingBox{MinLat: math.NaN(), MinLng: 51, MaxLat: 36, MaxLng: 52}))
+}
+
+func TestValidatePoints(t *testing.T) {
+	res := ValidatePoints("stops", []Point{{1, 1}, {91, 1}, {0, 0}}, 10)
+	assert.False(t, res.IsValid())
+	assert.Equal(t, "stops[1].lat", res.Errors[0].Name)
+	assert.Equal(t, LatitudeCode, res.Errors[0].Codes[0].Code)
+	assert.Equal(t, "stops[2]", res.Errors[1].Name)
+	assert.Equal(t, NullIslandCode, res.Errors[1].Codes[0].Code)
+
+	res = ValidatePoints("stops", make([]Point, 3), 2)
+	assert.Equal(t, MaxPointsCode, res.Errors[0].Codes[0].Code)
+
+	p := Point{Lat: 35.7, Lng: 51.4}
+	assert.True(t, Validate(&p).IsValid())
+}
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 )
@@ -9,14 +10,33 @@ type Validatable interface {
 	Validate() *Result // Validate must ALWAYS be declared on a pointer to a struct
 }
 
+var validatableType = reflect.TypeOf((*Validatable)(nil)).Elem()
+
 // Validate clean data (strips spaces from strings) and validates
 // Make sure to provide a pointer to the struct or else it will fail
+//
+// Validatable values nested in struct fields, slices, arrays and maps are
+// validated too and their errors are reported under the path of the value,
+// e.g. stops[3].lat. A nested Validatable is responsible for the values it
+// contains, they are not walked, and values reached again through a cycle
+// of pointers are validated once.
 func Validate(v Validatable) *Result {
 	trimSpaces(v)
-	return v.Validate()
+	out := NewResult()
+	if res := v.Validate(); res != nil {
+		out.Details, out.Code = res.Details, res.Code
+		out.AddResult(res)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		validateFields(out, "", rv.Elem(), map[uintptr]bool{rv.Pointer(): true})
+	}
+	return out
 }
 
-// will trim spaces from all string and *string fields of a struct
+// will trim spaces from all string and *string values of a struct and of
+// the structs, slices, arrays and maps it contains
 func trimSpaces(i interface{}) {
 	v := reflect.ValueOf(i)
 
@@ -25,22 +45,152 @@ func trimSpaces(i interface{}) {
 		return // nothing will happen
 	}
 
-	// need to dereference pointer to access fields
-	v = v.Elem()
+	trimValue(v, make(map[uintptr]bool))
+}
+
+func trimValue(v reflect.Value, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(strings.TrimSpace(v.String()))
+		}
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		// pointers may form cycles
+		seen[v.Pointer()] = true
+		trimValue(v.Elem(), seen)
+	case reflect.Interface:
+		if !v.IsNil() && v.Elem().Kind() == reflect.Ptr {
+			trimValue(v.Elem(), seen)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// unexported fields can not be set
+			if v.Field(i).CanSet() {
+				trimValue(v.Field(i), seen)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			trimValue(v.Index(i), seen)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		// map values are not addressable, trimmed copies are stored back
+		iter := v.MapRange()
+		for iter.Next() {
+			cp := reflect.New(iter.Value().Type()).Elem()
+			cp.Set(iter.Value())
+			trimValue(cp, seen)
+			v.SetMapIndex(iter.Key(), cp)
+		}
+	}
+}
 
+func validateFields(out *Result, path string, v reflect.Value, seen map[uintptr]bool) {
+	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
-		str, ok := v.Field(i).Interface().(string)
-		if ok {
-			// we can set if it is a simple string
-			str = strings.TrimSpace(str)
-			v.Field(i).SetString(str)
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue // unexported
 		}
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous {
+			// fields of embedded structs are promoted
+			validateValue(out, path, v.Field(i), seen)
+			continue
+		}
+		validateValue(out, fieldPath(path, name), v.Field(i), seen)
+	}
+}
 
-		ptr, ok := v.Field(i).Interface().(*string)
-		if ok && ptr != nil {
-			// if this is a pointer we set it like this
-			*ptr = strings.TrimSpace(*ptr)
-			v.Field(i).Set(reflect.ValueOf(ptr))
+func validateValue(out *Result, path string, v reflect.Value, seen map[uintptr]bool) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			if seen[v.Pointer()] {
+				return
+			}
+			// pointers may form cycles
+			seen[v.Pointer()] = true
 		}
+		if nested, ok := asValidatable(v); ok {
+			out.addNested(path, nested.Validate())
+			return
+		}
+		validateValue(out, path, v.Elem(), seen)
+		return
+	}
+	if v.CanAddr() {
+		if nested, ok := asValidatable(v.Addr()); ok {
+			out.addNested(path, nested.Validate())
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		validateFields(out, path, v, seen)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(out, indexPath(path, i), v.Index(i), seen)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// map values are not addressable, a copy is validated
+			cp := reflect.New(iter.Value().Type()).Elem()
+			cp.Set(iter.Value())
+			validateValue(out, fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), cp, seen)
+		}
+	}
+}
+
+func asValidatable(v reflect.Value) (Validatable, bool) {
+	if !v.Type().Implements(validatableType) || !v.CanInterface() {
+		return nil, false
+	}
+	nested, ok := v.Interface().(Validatable)
+	return nested, ok
+}
+
+// jsonName returns the name of a field in payloads, false for fields
+// ignored by encoding/json
+func jsonName(f reflect.StructField) (string, bool) {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	default:
+		return name, true
+	}
+}
+
+// addNested adds the errors of a nested result under path
+func (r *Result) addNested(path string, nested *Result) {
+	if nested == nil {
+		return
+	}
+	for _, e := range nested.Errors {
+		for _, ed := range e.Codes {
+			r.AddFieldError(fieldPath(path, e.Name), ed)
+		}
+	}
+	if nested.Details != "" && len(nested.Errors) == 0 {
+		r.AddFieldError(path, ErrorDetails{
+			Message: nested.Details,
+			Code:    nested.Code,
+		})
 	}
 }
@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAddress struct {
+	Street string  `json:"street"`
+	Note   *string `json:"note"`
+}
+
+type testStop struct {
+	Point
+	Name string `json:"name"`
+}
+
+func (s *testStop) Validate() *Result {
+	out := ValidatePoint("", s.Point)
+	if s.Name == "" {
+		out.AddFieldError("name", ErrorDetails{Message: "empty name", Code: "required"})
+	}
+	return out
+}
+
+type testOrder struct {
+	ID       string                 `json:"id"`
+	Address  testAddress            `json:"address"`
+	Stops    []testStop             `json:"stops"`
+	Dropoff  *testStop              `json:"dropoff"`
+	Tags     []string               `json:"tags"`
+	Labels   map[string]string      `json:"labels"`
+	Backups  map[string]testStop    `json:"backups"`
+	Ignored  *testStop              `json:"-"`
+	Extra    map[string]interface{} `json:"extra"`
+	internal string
+}
+
+func (o *testOrder) Validate() *Result {
+	out := NewResult()
+	if o.ID == "" {
+		out.AddFieldError("id", ErrorDetails{Message: "empty id", Code: "required"})
+	}
+	return out
+}
+
+func TestValidateTrimsNestedValues(t *testing.T) {
+	note := "  ring twice "
+	o := &testOrder{
+		ID:       " 42 ",
+		Address:  testAddress{Street: " Valiasr ", Note: &note},
+		Stops:    []testStop{{Point: Point{Lat: 35.7, Lng: 51.4}, Name: " first "}},
+		Tags:     []string{" a ", "b "},
+		Labels:   map[string]string{"k": " v "},
+		Backups:  map[string]testStop{"b": {Point: Point{Lat: 35.7, Lng: 51.4}, Name: " backup "}},
+		internal: " kept ",
+	}
+
+	assert.True(t, Validate(o).IsValid())
+	assert.Equal(t, "42", o.ID)
+	assert.Equal(t, "Valiasr", o.Address.Street)
+	assert.Equal(t, "ring twice", note)
+	assert.Equal(t, "first", o.Stops[0].Name)
+	assert.Equal(t, []string{"a", "b"}, o.Tags)
+	assert.Equal(t, "v", o.Labels["k"])
+	assert.Equal(t, "backup", o.Backups["b"].Name)
+	assert.Equal(t, " kept ", o.internal)
+}
+
+func TestValidateNestedPaths(t *testing.T) {
+	o := &testOrder{
+		Stops: []testStop{
+			{Point: Point{Lat: 35.7, Lng: 51.4}, Name: "first"},
+			{Point: Point{Lat: 95, Lng: 51.4}, Name: " "},
+		},
+		Dropoff: &testStop{Point: Point{Lat: 35.7, Lng: 200}, Name: "dropoff"},
+		Ignored: &testStop{},
+	}
+
+	res := Validate(o)
+	names := make([]string, 0, len(res.Errors))
+	for _, e := range res.Errors {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"id", "stops[1].lat", "stops[1].name", "dropoff.lng"}, names)
+}
+
+type testNode struct {
+	Name string    `json:"name"`
+	Next *testNode `json:"next"`
+	Stop *testStop `json:"stop"`
+}
+
+type testRoute struct {
+	Head *testNode `json:"head"`
+}
+
+func (r *testRoute) Validate() *Result {
+	return NewResult()
+}
+
+func TestValidateCycles(t *testing.T) {
+	a := &testNode{Name: " a ", Stop: &testStop{Point: Point{Lat: 95, Lng: 51.4}, Name: "a"}}
+	b := &testNode{Name: " b ", Next: a}
+	a.Next = b
+
+	res := Validate(&testRoute{Head: a})
+	assert.Equal(t, "a", a.Name)
+	assert.Equal(t, "b", b.Name)
+	assert.Len(t, res.Errors, 1)
+	assert.Equal(t, "head.stop.lat", res.Errors[0].Name)
+}
+
+func TestValidateNestedOnce(t *testing.T) {
+	o := &testOrder{
+		ID:    "42",
+		Stops: []testStop{{Point: Point{Lat: 95, Lng: 51.4}, Name: "first"}},
+	}
+
+	res := Validate(o)
+	assert.Len(t, res.Errors, 1)
+	// the embedded Point is validated by testStop.Validate only
+	assert.Len(t, res.Errors[0].Codes, 1)
+}
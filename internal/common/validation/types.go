@@ -3,13 +3,68 @@ package validation
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 )
 
 // TODO: move to any errors, not only validation?
 
+const unknownField = "unknown"
+
+// Codes of request errors, InvalidValueCode is the code of the field
+// errors of UnmarshalDetailedError
 const (
-	notImplementedCode = "not_implemented"
-	unknownField       = "unknown"
+	InvalidBodyCode       = "invalid_body"
+	DBOperationCode       = "db_operation"
+	BothEmailAndPhoneCode = "both_email_and_phone"
+	CaptchaCode           = "captcha"
+	InvalidValueCode      = "invalid_value"
+)
+
+// Codes of account validation errors
+const (
+	EitherPhoneOrEmailCode  = "either_phone_or_email"
+	EmptyBirthDateCode      = "empty_birth_date"
+	NotOnlyLettersCode      = "not_only_letters"
+	UserAlreadyExistsCode   = "user_already_exists"
+	UserNotFoundCode        = "user_not_found"
+	UnauthorizedCode        = "unauthorized"
+	EmptyPasswordCode       = "empty_password"
+	PasswordCode            = "invalid_password"
+	RulesNotAcceptedCode    = "rules_not_accepted"
+	NameTooShortCode        = "name_too_short"
+	TooYoungAgeCode         = "too_young_age"
+	PhoneFormatCode         = "wrong_phone_format"
+	EmailCode               = "invalid_email"
+	UnknownCountryCode      = "unknown_country"
+	PhoneCode               = "invalid_phone"
+	IPAddressCode           = "invalid_ip_address"
+	EmptyDeviceCode         = "empty_device"
+	EmptyRefreshTokenCode   = "empty_refresh_token"
+	AntiPhishingCode        = "invalid_anti_phishing_code"
+	CallingCodeFormatCode   = "invalid_country_calling_code_format"
+	CallingCodeMismatchCode = "wrong_country_calling_code"
+	PageLimitCode           = "invalid_page_limit"
+	OrderColumnCode         = "invalid_order_column"
+	OtpCode                 = "invalid_otp_code"
+	ActionCode              = "invalid_action"
+	TwoFAMethodCode         = "invalid_2fa_method"
+	CodeFieldCode           = "invalid_code"
+	KeyCode                 = "invalid_key"
+	ResetTokenCode          = "invalid_reset_token"
+	ConfirmPasswordCode     = "invalid_confirm_password"
+	KeysCode                = "invalid_keys"
+)
+
+// Codes of geo validation errors, they match the validator tags checking
+// the same rules
+const (
+	LatitudeCode         = "lat"
+	LongitudeCode        = "lng"
+	NullIslandCode       = "not_null_island"
+	PolygonCode          = "polygon"
+	SelfIntersectionCode = "polygon_self_intersection"
+	BoundingBoxCode      = "bbox"
+	MaxPointsCode        = "max_points"
 )
 
 // structure of backend errors
@@ -28,6 +83,8 @@ type Error struct {
 type ErrorDetails struct {
 	Message string `json:"message"`
 	Code    string `json:"code"`
+	// Params fill the placeholders of the localized message of Code
+	Params map[string]string `json:"-"`
 }
 
 func NewResult() *Result {
@@ -40,7 +97,7 @@ func NewResult() *Result {
 func UnmarshalError(err error) *Result {
 	return &Result{
 		Details: err.Error(),
-		Code:    notImplementedCode,
+		Code:    InvalidBodyCode,
 	}
 }
 
@@ -50,7 +107,7 @@ var (
 )
 
 func UnmarshalDetailedError(err error) *Result {
-	return unmarshalError(notImplementedCode, err)
+	return unmarshalError(InvalidValueCode, err)
 }
 
 func unmarshalError(code string, err error) *Result {
@@ -72,6 +129,7 @@ func unmarshalError(code string, err error) *Result {
 	out.AddFieldError(fieldName, ErrorDetails{
 		Message: errorDetails,
 		Code:    code,
+		Params:  map[string]string{"details": errorDetails},
 	})
 
 	return out
@@ -80,21 +138,21 @@ func unmarshalError(code string, err error) *Result {
 func DBOperationError(err error) *Result {
 	return &Result{
 		Details: err.Error(),
-		Code:    notImplementedCode,
+		Code:    DBOperationCode,
 	}
 }
 
 func BothEmailAndPhoneProvided() *Result {
 	return &Result{
 		Details: "can't provide both email and phone during registration",
-		Code:    notImplementedCode,
+		Code:    BothEmailAndPhoneCode,
 	}
 }
 
 func CaptchaError(err error) *Result {
 	return &Result{
 		Details: err.Error(),
-		Code:    notImplementedCode,
+		Code:    CaptchaCode,
 	}
 }
 
@@ -149,216 +207,267 @@ func (r *Result) AddCode(code string) *Result {
 func EitherPhoneOrEmail() ErrorDetails {
 	return ErrorDetails{
 		Message: "either phone or e-mail must be provided",
-		Code:    notImplementedCode,
+		Code:    EitherPhoneOrEmailCode,
 	}
 }
 
 func EmptyBirthDate() ErrorDetails {
 	return ErrorDetails{
 		Message: "empty birthday",
-		Code:    notImplementedCode,
+		Code:    EmptyBirthDateCode,
 	}
 }
 
 func NotOnlyLetters() ErrorDetails {
 	return ErrorDetails{
 		Message: "field must contain only letters",
-		Code:    notImplementedCode,
+		Code:    NotOnlyLettersCode,
 	}
 }
 
 func UserAlreadyExists() ErrorDetails {
 	return ErrorDetails{
 		Message: "user already exists",
-		Code:    notImplementedCode,
+		Code:    UserAlreadyExistsCode,
 	}
 }
 
 func UserNotFound() ErrorDetails {
 	return ErrorDetails{
 		Message: "user not found",
-		Code:    notImplementedCode,
+		Code:    UserNotFoundCode,
 	}
 }
 
 func Unauthorized() ErrorDetails {
 	return ErrorDetails{
 		Message: "unauthorized",
-		Code:    notImplementedCode,
+		Code:    UnauthorizedCode,
 	}
 }
 
 func EmptyPassword() ErrorDetails {
 	return ErrorDetails{
 		Message: "empty password provided",
-		Code:    notImplementedCode,
+		Code:    EmptyPasswordCode,
 	}
 }
 
 func InvalidPassword() ErrorDetails {
 	return ErrorDetails{
 		Message: "password must contain at least 1 lowercased letter, 1 capital letter, 1 digit, 1 special char and be minimum 8 chars long",
-		Code:    notImplementedCode,
+		Code:    PasswordCode,
 	}
 }
 
 func RulesNotAccepted() ErrorDetails {
 	return ErrorDetails{
 		Message: "rules were not accepted",
-		Code:    notImplementedCode,
+		Code:    RulesNotAcceptedCode,
 	}
 }
 
 func NameIsTooShort() ErrorDetails {
 	return ErrorDetails{
 		Message: "name cannot be less than 2 characters",
-		Code:    notImplementedCode,
+		Code:    NameTooShortCode,
 	}
 }
 
 func TooYoungAge() ErrorDetails {
 	return ErrorDetails{
 		Message: "age must be more than 18 years",
-		Code:    notImplementedCode,
+		Code:    TooYoungAgeCode,
 	}
 }
 
 func WrongPhoneFormat() ErrorDetails {
 	return ErrorDetails{
 		Message: "wrong phone format: it should contain only digits",
-		Code:    notImplementedCode,
+		Code:    PhoneFormatCode,
 	}
 }
 
 func InvalidEmail() ErrorDetails {
 	return ErrorDetails{
 		Message: "email is empty or has invalid format",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    EmailCode,
 	}
 }
 
 func UnknownCountry() ErrorDetails {
 	return ErrorDetails{
 		Message: "such country does not exist",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    UnknownCountryCode,
 	}
 }
 
 func InvalidPhone() ErrorDetails {
 	return ErrorDetails{
 		Message: "phone is empty",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    PhoneCode,
 	}
 }
 
 func InvalidIPAddress(ip string) ErrorDetails {
 	return ErrorDetails{
 		Message: fmt.Sprintf("ip_address is empty or has invalid format: %s", ip),
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    IPAddressCode,
+		Params:  map[string]string{"ip": ip},
 	}
 }
 
 func EmptyDevice() ErrorDetails {
 	return ErrorDetails{
 		Message: "empty device provided",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    EmptyDeviceCode,
 	}
 }
 
 func EmptyRefreshToken() ErrorDetails {
 	return ErrorDetails{
 		Message: "empty refresh token provided",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    EmptyRefreshTokenCode,
 	}
 }
 
 func InvalidAntiPhishingCode() ErrorDetails {
 	return ErrorDetails{
 		Message: "anti-phishing code is invalid",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    AntiPhishingCode,
 	}
 }
 
 func InvalidCountryCallingCodeFormat() ErrorDetails {
 	return ErrorDetails{
 		Message: "country calling code format is invalid",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    CallingCodeFormatCode,
 	}
 }
 
 func WrongCountryCallingCode() ErrorDetails {
 	return ErrorDetails{
 		Message: "country calling code does not match selected country",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    CallingCodeMismatchCode,
 	}
 }
 
 func InvalidPageLimit() ErrorDetails {
 	return ErrorDetails{
 		Message: "page limit is invalid",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    PageLimitCode,
 	}
 }
 
 func InvalidOrderColumn() ErrorDetails {
 	return ErrorDetails{
 		Message: "order column is invalid",
-		Code:    notImplementedCode, // TODO error code should be implemented
+		Code:    OrderColumnCode,
 	}
 }
 
 func InvalidOtpCode() ErrorDetails {
 	return ErrorDetails{
 		Message: "invalid otp code provided",
-		Code:    notImplementedCode,
+		Code:    OtpCode,
 	}
 }
 
 func InvalidAction() ErrorDetails {
 	return ErrorDetails{
 		Message: "action is invalid",
-		Code:    notImplementedCode,
+		Code:    ActionCode,
 	}
 }
 
 func Invalid2FAMethod() ErrorDetails {
 	return ErrorDetails{
 		Message: "method is invalid",
-		Code:    notImplementedCode,
+		Code:    TwoFAMethodCode,
 	}
 }
 
 func InvalidCode() ErrorDetails {
 	return ErrorDetails{
 		Message: "code is invalid",
-		Code:    notImplementedCode,
+		Code:    CodeFieldCode,
 	}
 }
 
 func InvalidKey() ErrorDetails {
 	return ErrorDetails{
 		Message: "key is empty",
-		Code:    notImplementedCode,
+		Code:    KeyCode,
 	}
 }
 
 func InvalidResetToken() ErrorDetails {
 	return ErrorDetails{
 		Message: "reset-token is invalid",
-		Code:    notImplementedCode,
+		Code:    ResetTokenCode,
 	}
 }
 
 func InvalidConfirmPassword() ErrorDetails {
 	return ErrorDetails{
 		Message: "confirm password is invalid",
-		Code:    notImplementedCode,
+		Code:    ConfirmPasswordCode,
 	}
 }
 
 func InvalidKeys() ErrorDetails {
 	return ErrorDetails{
 		Message: "phone or email method is missing",
-		Code:    notImplementedCode,
+		Code:    KeysCode,
+	}
+}
+
+func InvalidLatitude() ErrorDetails {
+	return ErrorDetails{
+		Message: "latitude must be a number between -90 and 90",
+		Code:    LatitudeCode,
+	}
+}
+
+func InvalidLongitude() ErrorDetails {
+	return ErrorDetails{
+		Message: "longitude must be a number between -180 and 180",
+		Code:    LongitudeCode,
+	}
+}
+
+func NullIsland() ErrorDetails {
+	return ErrorDetails{
+		Message: "location 0,0 is not accepted",
+		Code:    NullIslandCode,
+	}
+}
+
+func InvalidPolygon() ErrorDetails {
+	return ErrorDetails{
+		Message: "polygon must have at least 3 distinct points and a non-zero area",
+		Code:    PolygonCode,
+	}
+}
+
+func SelfIntersectingPolygon() ErrorDetails {
+	return ErrorDetails{
+		Message: "polygon edges must not intersect",
+		Code:    SelfIntersectionCode,
+	}
+}
+
+func InvalidBoundingBox() ErrorDetails {
+	return ErrorDetails{
+		Message: "bounding box corners are invalid",
+		Code:    BoundingBoxCode,
+	}
+}
+
+func TooManyPoints(max int) ErrorDetails {
+	return ErrorDetails{
+		Message: fmt.Sprintf("at most %d points are accepted", max),
+		Code:    MaxPointsCode,
+		Params:  map[string]string{"param": strconv.Itoa(max)},
 	}
 }
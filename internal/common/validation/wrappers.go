@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -52,3 +53,22 @@ func ValidateAntiPhishingCode(in string) *Result {
 
 	return out
 }
+
+// fieldPath joins the name of a nested field to its parent, e.g. stops[3].lat
+func fieldPath(parent, field string) string {
+	if parent == "" {
+		return field
+	}
+	return parent + "." + field
+}
+
+func indexPath(field string, i int) string {
+	return field + "[" + strconv.Itoa(i) + "]"
+}
+
+func fieldOrDefault(field, def string) string {
+	if field == "" {
+		return def
+	}
+	return field
+}
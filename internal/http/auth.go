@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/labstack/echo/v4"
+)
+
+// Authenticate returns a middleware which identifies the client with the
+// first authenticator finding credentials in the request. The principal is
+// stored in the request context for auth.FromContext and its subject under
+// httpx.ContextKeyUserID.
+func Authenticate(logger *loggerx.Logger, authenticators ...auth.Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			for _, a := range authenticators {
+				p, err := a.Authenticate(r)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					logger.Debug("authentication failed", loggerx.Error(err))
+					return unauthorized(c)
+				}
+
+				ctx := auth.WithPrincipal(r.Context(), p)
+				ctx = context.WithValue(ctx, httpx.ContextKeyUserID, p.Subject)
+				c.SetRequest(r.WithContext(ctx))
+				return next(c)
+			}
+			return unauthorized(c)
+		}
+	}
+}
+
+// RequireScope rejects authenticated clients missing one of scopes
+func RequireScope(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p := auth.FromContext(c.Request().Context())
+			if p == nil {
+				return unauthorized(c)
+			}
+			for _, scope := range scopes {
+				if !p.HasScope(scope) {
+					return errorx.ErrForbidden
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+func unauthorized(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return errorx.ErrUnauthorized
+}
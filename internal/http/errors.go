@@ -0,0 +1,73 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/i18n"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorHandler renders every error returned by handlers and middlewares
+// as errorx.Error. Server errors are logged with their cause, which is not
+// sent to the client.
+func ErrorHandler(logger *loggerx.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			err = fromHTTPError(he)
+		}
+
+		res := errorResponse(c, err)
+		if res.StatusCode >= http.StatusInternalServerError {
+			logger.Error("request failed", loggerx.Error(err), loggerx.TraceID(res.TraceID))
+		}
+
+		var renderErr error
+		if c.Request().Method == http.MethodHead {
+			renderErr = c.NoContent(res.StatusCode)
+		} else {
+			renderErr = c.JSON(res.StatusCode, res)
+		}
+		if renderErr != nil {
+			logger.Error("failed to render error", loggerx.Error(renderErr))
+		}
+	}
+}
+
+// errorResponse renders err in the locale of the request
+func errorResponse(c echo.Context, err error) errorx.Error {
+	ctx := c.Request().Context()
+	locale := i18n.FromContext(ctx)
+
+	res := errorx.NewErrorResponse(err)
+	res.Error = i18n.Error(locale, res.Code, res.Error)
+	if fields, ok := res.DetailErrors.([]*validation.Error); ok {
+		res.DetailErrors = i18n.Fields(locale, fields)
+	}
+	res.TraceID = tracing.TraceID(ctx)
+	return res
+}
+
+func fromHTTPError(he *echo.HTTPError) error {
+	message := http.StatusText(he.Code)
+	if m, ok := he.Message.(string); ok {
+		message = m
+	} else if he.Message != nil {
+		message = fmt.Sprintf("%v", he.Message)
+	}
+	out := errorx.FromHTTPStatus(he.Code, message)
+	if he.Internal != nil {
+		return out.Wrap(he.Internal)
+	}
+	return out
+}
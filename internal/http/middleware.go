@@ -1 +1,177 @@
-package http
\ No newline at end of file
+package http
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/i18n"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// DefaultRequestLoggerConfig is the default RequestLogger middleware config.
+	DefaultRequestLoggerConfig = RequestLoggerConfig{
+		SkipPaths:  []string{"/livez", "/healthz", "/readyz", "/metrics"},
+		SampleRate: 1,
+	}
+)
+
+// RequestLoggerConfig defines the config for RequestLogger middleware.
+type RequestLoggerConfig struct {
+	// SkipPaths are request paths which are never logged, e.g. probes
+	SkipPaths []string
+
+	// SampleRate is the fraction of successful requests which are logged,
+	// failed requests are always logged
+	SampleRate float64
+}
+
+// RequestLogger returns a middleware which logs every request through
+// loggerx with its httpx.RequestInfo.
+func RequestLogger(logger *loggerx.Logger, config RequestLoggerConfig) echo.MiddlewareFunc {
+	skip := make(map[string]struct{}, len(config.SkipPaths))
+	for _, p := range config.SkipPaths {
+		skip[strings.TrimSuffix(p, "/")] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			if _, ok := skip[r.URL.Path]; ok {
+				return next(c)
+			}
+
+			ri := httpx.NewRequestInfo(r)
+			httpx.AddToContext(r, httpx.RequestInfoKey, ri)
+			c.SetRequest(r)
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				// render the error so the logged status is the one sent, it
+				// is rendered once so the error is not returned
+				c.Error(err)
+			}
+
+			res := c.Response()
+			ri.Code = res.Status
+			ri.Size = res.Size
+			ri.Duration = time.Since(start)
+			ri.TraceID = tracing.TraceID(r.Context())
+			ri.RequestID = res.Header().Get(echo.HeaderXRequestID)
+
+			if ri.Code < http.StatusBadRequest {
+				if config.SampleRate < 1 && rand.Float64() >= config.SampleRate { //nolint:gosec
+					return nil
+				}
+				logger.Info(r.URL.String(), loggerx.Any("request", ri))
+			} else {
+				logger.Warn(r.URL.String(), loggerx.Any("request", ri))
+			}
+
+			return nil
+		}
+	}
+}
+
+// Recover returns a middleware which recovers from panics in handlers,
+// renders an errorx.Error 500 response, logs the stack and reports the
+// panic to sentry tagged with the request. sentryer may be nil.
+func Recover(logger *loggerx.Logger, sentryer loggerx.Sentryer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if r == http.ErrAbortHandler { //nolint:errorlint
+					// sentinel used to abort a response, handled by net/http
+					panic(r)
+				}
+
+				err, ok := r.(error)
+				if !ok {
+					err = fmt.Errorf("%v", r)
+				}
+				pw := loggerx.NewPanicWrapper(err)
+
+				tags := panicTags(c)
+				if sentryer != nil {
+					scope := sentry.NewScope()
+					scope.SetLevel(sentry.LevelFatal)
+					scope.SetTags(tags)
+					scope.SetRequest(c.Request())
+					sentryer.Recover(pw, &sentry.EventHint{RecoveredException: pw, Context: c.Request().Context()}, scope)
+				}
+
+				fields := []loggerx.Field{
+					loggerx.Error(pw),
+					loggerx.String("stack", string(debug.Stack())),
+					loggerx.SentryReported(),
+				}
+				for k, v := range tags {
+					fields = append(fields, loggerx.String(k, v))
+				}
+				logger.Error("panic recovered", fields...)
+
+				if !c.Response().Committed {
+					res := errorResponse(c, errorx.ErrInternal.Wrap(err))
+					_ = c.JSON(res.StatusCode, res)
+				}
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+const (
+	requestIDTag = "request_id"
+	routeTag     = "route"
+	userTag      = "user"
+	traceIDTag   = "trace_id"
+)
+
+func panicTags(c echo.Context) map[string]string {
+	tags := map[string]string{
+		routeTag: c.Request().Method + " " + c.Path(),
+	}
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		tags[requestIDTag] = id
+	}
+	if user := c.Request().Context().Value(httpx.ContextKeyUserID); user != nil {
+		tags[userTag] = fmt.Sprintf("%v", user)
+	}
+	if id := tracing.TraceID(c.Request().Context()); id != "" {
+		tags[traceIDTag] = id
+	}
+	return tags
+}
+
+// Locale negotiates the locale of the response from the Accept-Language
+// header and stores it in the request context for i18n.FromContext.
+func Locale() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			locale := i18n.Negotiate(c.Request().Header.Get(headerAcceptLanguage))
+			c.SetRequest(c.Request().WithContext(i18n.WithLocale(c.Request().Context(), locale)))
+			c.Response().Header().Add(echo.HeaderVary, headerAcceptLanguage)
+			c.Response().Header().Set(headerContentLanguage, locale.String())
+			return next(c)
+		}
+	}
+}
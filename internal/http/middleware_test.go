@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+)
+
+// fakeSentryer records the events instead of sending them
+type fakeSentryer struct {
+	recovered []interface{}
+	scopes    []*sentry.Scope
+}
+
+func (f *fakeSentryer) Flush(_ time.Duration) bool {
+	return true
+}
+
+func (f *fakeSentryer) Recover(err interface{}, _ *sentry.EventHint, scope sentry.EventModifier) *sentry.EventID {
+	f.recovered = append(f.recovered, err)
+	f.scopes = append(f.scopes, scope.(*sentry.Scope))
+	return nil
+}
+
+func (f *fakeSentryer) CaptureException(_ error, _ *sentry.EventHint, _ sentry.EventModifier) *sentry.EventID {
+	return nil
+}
+
+func (f *fakeSentryer) CaptureMessage(_ string, _ *sentry.EventHint, _ sentry.EventModifier) *sentry.EventID {
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	fake := &fakeSentryer{}
+	e := echo.New()
+	e.Use(middleware.RequestID())
+	e.Use(Recover(loggerx.NewTestLogger(), fake))
+	e.GET("/couriers/:id", func(c echo.Context) error {
+		panic(errors.New("boom"))
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/couriers/42", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var body errorx.Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "INTERNAL", body.Code)
+	assert.Equal(t, http.StatusInternalServerError, body.StatusCode)
+
+	require.Len(t, fake.recovered, 1)
+	pw, ok := fake.recovered[0].(*loggerx.PanicWrapper)
+	require.True(t, ok)
+	assert.EqualError(t, pw, "boom")
+
+	event := fake.scopes[0].ApplyToEvent(sentry.NewEvent(), nil)
+	assert.Equal(t, "GET /couriers/:id", event.Tags[routeTag])
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), event.Tags[requestIDTag])
+	assert.Equal(t, sentry.LevelFatal, event.Level)
+}
+
+func TestRecoverWithoutSentry(t *testing.T) {
+	e := echo.New()
+	e.Use(Recover(loggerx.NewTestLogger(), nil))
+	e.GET("/", func(c echo.Context) error {
+		panic("not an error")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestRequestLoggerError(t *testing.T) {
+	e := echo.New()
+	var rendered int
+	handler := ErrorHandler(loggerx.NewTestLogger())
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		rendered++
+		handler(err, c)
+	}
+	e.Use(RequestLogger(loggerx.NewTestLogger(), DefaultRequestLoggerConfig))
+	e.Use(Recover(loggerx.NewTestLogger(), nil))
+	e.GET("/forbidden", func(c echo.Context) error {
+		return errorx.ErrForbidden
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forbidden", nil))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, 1, rendered, "the error is rendered once")
+	var body errorx.Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusForbidden, body.StatusCode)
+}
+
+func TestErrorHandlerMethodNotAllowed(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(loggerx.NewTestLogger())
+	e.GET("/couriers", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(method, "/couriers", nil))
+
+			assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+			var body errorx.Error
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, errorx.CodeMethodNotAllowed, body.Code)
+			assert.Equal(t, http.StatusMethodNotAllowed, body.StatusCode)
+		})
+	}
+}
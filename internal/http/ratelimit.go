@@ -0,0 +1,71 @@
+package http
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerQuotaLimit         = "X-Quota-Limit"
+	headerQuotaRemaining     = "X-Quota-Remaining"
+)
+
+// RateLimit returns a middleware limiting requests per client. It must run
+// after Authenticate: authenticated clients are limited by principal and
+// anonymous ones by IP address. Requests are let through if the store
+// fails.
+func RateLimit(logger *loggerx.Logger, limiter *ratelimit.Limiter) echo.MiddlewareFunc {
+	policy := limiter.Policy()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			route := r.Method + " " + c.Path()
+
+			d, err := limiter.Allow(r.Context(), rateLimitClient(c), route)
+			if err != nil {
+				logger.Warn("rate limit store failed", loggerx.Error(err))
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			if !d.Limit.Unlimited() {
+				h.Set(headerRateLimitLimit, strconv.Itoa(d.Limit.Burst))
+				h.Set(headerRateLimitRemaining, strconv.Itoa(d.Remaining))
+				h.Set(headerRateLimitReset, ceilSeconds(d.Reset))
+			}
+			if policy.DailyQuota > 0 && (d.Allowed || d.QuotaExceeded) {
+				h.Set(headerQuotaLimit, strconv.FormatInt(policy.DailyQuota, 10))
+				h.Set(headerQuotaRemaining, strconv.FormatInt(d.QuotaRemaining, 10))
+			}
+			if !d.Allowed {
+				ratelimit.ObserveRejected(c.Path(), d)
+				h.Set(headerRetryAfter, ceilSeconds(d.RetryAfter))
+				return errorx.ErrTooManyRequests
+			}
+			return next(c)
+		}
+	}
+}
+
+func rateLimitClient(c echo.Context) string {
+	if p := auth.FromContext(c.Request().Context()); p != nil {
+		return p.Method + ":" + p.Subject
+	}
+	return "ip:" + httpx.GetRequestInfo(c.Request()).IPAddr
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
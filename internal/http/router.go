@@ -1,21 +1,64 @@
 package http
 
 import (
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/metrics"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+	"github.com/opentracing/opentracing-go"
 )
 
-func InitRouter() *echo.Echo {
+func InitRouter(cfg *config.Config, logger *loggerx.Logger, tracer opentracing.Tracer) *echo.Echo {
 	e := echo.New()
+	// c.RealIP() resolves the client behind the trusted proxies
+	e.IPExtractor = httpx.ClientIP
 	e.Logger.SetLevel(log.DEBUG)
 	e.Pre(middleware.RemoveTrailingSlash())
-	e.Use(middleware.Logger())
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PATCH, echo.PUT, echo.OPTIONS, echo.DELETE},
+	e.Use(tracing.EchoTrace(tracer))
+	e.Use(middleware.RequestID())
+	e.Use(Locale())
+	e.Use(RequestLogger(logger.Named("http"), requestLoggerConfig(cfg)))
+	e.Use(Recover(logger.Named("http"), logger.Sentry()))
+	e.Use(metrics.Middleware())
+	// HSTS is only sent over TLS or behind a proxy terminating it
+	e.Use(middleware.SecureWithConfig(middleware.SecureConfig{
+		XSSProtection:         "0",
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         "DENY",
+		HSTSMaxAge:            cfg.Server.Security.HSTSMaxAge,
+		ContentSecurityPolicy: cfg.Server.Security.CSP,
+		ReferrerPolicy:        "no-referrer",
 	}))
+	if len(cfg.Server.CORS.AllowOrigins) > 0 {
+		e.Use(middleware.CORSWithConfig(corsConfig(cfg.Server.CORS)))
+	}
 	e.Validator = NewValidator()
+	e.HTTPErrorHandler = ErrorHandler(logger.Named("http"))
 	return e
 }
+
+func requestLoggerConfig(cfg *config.Config) RequestLoggerConfig {
+	c := DefaultRequestLoggerConfig
+	if cfg.Logging.Request.SkipPaths != nil {
+		c.SkipPaths = cfg.Logging.Request.SkipPaths
+	}
+	c.SampleRate = cfg.Logging.Request.SampleRate
+	return c
+}
+
+// corsConfig returns the CORS config of the browser clients allowed by cfg,
+// preflight requests of other origins get no CORS headers
+func corsConfig(cfg config.CORS) middleware.CORSConfig {
+	return middleware.CORSConfig{
+		AllowOrigins:     cfg.AllowOrigins,
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		ExposeHeaders:    cfg.ExposeHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+}
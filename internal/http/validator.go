@@ -1,12 +1,33 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/errorx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/i18n"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
 	"gopkg.in/go-playground/validator.v9"
 )
 
 func NewValidator() *Validator {
+	v := validator.New()
+	// report fields by their JSON name
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	registerGeoValidations(v)
 	return &Validator{
-		validator: validator.New(),
+		validator: v,
 	}
 }
 
@@ -14,6 +35,40 @@ type Validator struct {
 	validator *validator.Validate
 }
 
+// Validate returns errorx.ErrValidation with an entry per invalid field
 func (v *Validator) Validate(i interface{}) error {
-	return v.validator.Struct(i)
+	err := v.validator.Struct(i)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	return errorx.FromValidation(validationResult(fieldErrs))
+}
+
+func validationResult(fieldErrs validator.ValidationErrors) *validation.Result {
+	out := validation.NewResult()
+	for _, fe := range fieldErrs {
+		params := map[string]string{"param": fe.Param()}
+		msg, ok := i18n.Message(i18n.Default, fe.Tag(), params)
+		if !ok {
+			msg = fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+		}
+		out.AddFieldError(fieldPath(fe), validation.ErrorDetails{
+			Message: msg,
+			Code:    fe.Tag(),
+			Params:  params,
+		})
+	}
+	return out
+}
+
+// fieldPath strips the name of the validated struct from the namespace,
+// e.g. "request.stops[3].lat" becomes "stops[3].lat"
+func fieldPath(fe validator.FieldError) string {
+	// errors of struct level rules are reported on the struct itself
+	ns := strings.TrimSuffix(fe.Namespace(), ".")
+	if idx := strings.Index(ns, "."); idx != -1 {
+		return ns[idx+1:]
+	}
+	return ns
 }
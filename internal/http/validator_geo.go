@@ -0,0 +1,70 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/validation"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// registerGeoValidations adds the geo rules of the validation package:
+//
+//	lat, lng       on float fields
+//	polygon        on []validation.Point and validation.Polygon fields
+//	max_points=N   on slices of points
+//
+// validation.Point and validation.BoundingBox fields are checked without
+// tags, a point must have a valid latitude and longitude and not be 0,0.
+func registerGeoValidations(v *validator.Validate) {
+	mustRegister(v, validation.LatitudeCode, func(fl validator.FieldLevel) bool {
+		return validation.IsLatitudeValid(fl.Field().Float())
+	})
+	mustRegister(v, validation.LongitudeCode, func(fl validator.FieldLevel) bool {
+		return validation.IsLongitudeValid(fl.Field().Float())
+	})
+	mustRegister(v, validation.PolygonCode, func(fl validator.FieldLevel) bool {
+		switch points := fl.Field().Interface().(type) {
+		case []validation.Point:
+			return validation.IsPolygonValid(points)
+		case validation.Polygon:
+			return validation.IsPolygonValid(points)
+		default:
+			return false
+		}
+	})
+	mustRegister(v, validation.MaxPointsCode, func(fl validator.FieldLevel) bool {
+		max, err := strconv.Atoi(fl.Param())
+		if err != nil {
+			panic("max_points: invalid limit " + fl.Param())
+		}
+		return fl.Field().Len() <= max
+	})
+
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		p := sl.Current().Interface().(validation.Point)
+		latValid := validation.IsLatitudeValid(p.Lat)
+		lngValid := validation.IsLongitudeValid(p.Lng)
+		if !latValid {
+			sl.ReportError(p.Lat, "lat", "Lat", validation.LatitudeCode, "")
+		}
+		if !lngValid {
+			sl.ReportError(p.Lng, "lng", "Lng", validation.LongitudeCode, "")
+		}
+		if latValid && lngValid && validation.IsNullIsland(p.Lat, p.Lng) {
+			sl.ReportError(p, "", "", validation.NullIslandCode, "")
+		}
+	}, validation.Point{})
+
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		b := sl.Current().Interface().(validation.BoundingBox)
+		if !validation.IsBoundingBoxValid(b) {
+			sl.ReportError(b, "", "", validation.BoundingBoxCode, "")
+		}
+	}, validation.BoundingBox{})
+}
+
+func mustRegister(v *validator.Validate, tag string, fn validator.Func) {
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,134 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of courier location files
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format, must be json or csv")
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+// csvHeader is the header of CSV files, updated_at is RFC 3339 or empty
+var csvHeader = []string{"courier_id", "lat", "lng", "updated_at"}
+
+// ReadLocations reads courier locations as a JSON list or CSV with a header
+func ReadLocations(r io.Reader, format string) ([]DeliverManLocation, error) {
+	switch format {
+	case FormatJSON:
+		var locs []DeliverManLocation
+		if err := json.NewDecoder(r).Decode(&locs); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		return locs, nil
+	case FormatCSV:
+		return readCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// WriteLocations writes courier locations in the format of ReadLocations
+func WriteLocations(w io.Writer, format string, locs []DeliverManLocation) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if locs == nil {
+			locs = []DeliverManLocation{}
+		}
+		return enc.Encode(locs)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(csvHeader)
+		for _, loc := range locs {
+			updatedAt := ""
+			if !loc.UpdatedAt.IsZero() {
+				updatedAt = loc.UpdatedAt.Format(time.RFC3339)
+			}
+			_ = cw.Write([]string{
+				loc.CourierID,
+				strconv.FormatFloat(loc.Lat, 'f', -1, 64),
+				strconv.FormatFloat(loc.Lng, 'f', -1, 64),
+				updatedAt,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// FormatOf returns the format of a file name, empty if it is unknown
+func FormatOf(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(name, ".csv"):
+		return FormatCSV
+	default:
+		return ""
+	}
+}
+
+func readCSV(r io.Reader) ([]DeliverManLocation, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"lat", "lng"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: header has no %s column", ErrInvalidRecord, name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	locs := make([]DeliverManLocation, 0, len(records)-1)
+	for n, record := range records[1:] {
+		line := n + 2
+		loc := DeliverManLocation{CourierID: field(record, "courier_id")}
+		if loc.Lat, err = strconv.ParseFloat(field(record, "lat"), 64); err != nil {
+			return nil, fmt.Errorf("%w on line %d: lat: %v", ErrInvalidRecord, line, err)
+		}
+		if loc.Lng, err = strconv.ParseFloat(field(record, "lng"), 64); err != nil {
+			return nil, fmt.Errorf("%w on line %d: lng: %v", ErrInvalidRecord, line, err)
+		}
+		if v := field(record, "updated_at"); v != "" {
+			if loc.UpdatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, fmt.Errorf("%w on line %d: updated_at: %v", ErrInvalidRecord, line, err)
+			}
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
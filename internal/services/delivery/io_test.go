@@ -0,0 +1,64 @@
+package delivery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocationsRoundTrip(t *testing.T) {
+	locs := []DeliverManLocation{
+		{CourierID: "c1", Lat: 35.6997, Lng: 51.338, UpdatedAt: time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{CourierID: "c2", Lat: -33.9249, Lng: 18.4241},
+	}
+	for _, format := range []string{FormatJSON, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WriteLocations(&buf, format, locs))
+			got, err := ReadLocations(&buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, locs, got)
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []DeliverManLocation
+		err  string
+	}{
+		{
+			name: "columns in any order",
+			csv:  "lng, lat, courier_id\n51.4, 35.7, c1\n",
+			want: []DeliverManLocation{{CourierID: "c1", Lat: 35.7, Lng: 51.4}},
+		},
+		{name: "missing column", csv: "courier_id,lat\nc1,35.7\n", err: "no lng column"},
+		{name: "invalid number", csv: "courier_id,lat,lng\nc1,north,51.4\n", err: "line 2: lat"},
+		{name: "invalid time", csv: "courier_id,lat,lng,updated_at\nc1,35.7,51.4,yesterday\n", err: "line 2: updated_at"},
+		{name: "empty", csv: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadLocations(strings.NewReader(tt.csv), FormatCSV)
+			if tt.err != "" {
+				assert.ErrorIs(t, err, ErrInvalidRecord)
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	assert.InDelta(t, 0, Distance(35.7, 51.4, 35.7, 51.4), 1e-9)
+	// Tehran to Isfahan
+	assert.InDelta(t, 338, Distance(35.6892, 51.389, 32.6539, 51.666), 2)
+}
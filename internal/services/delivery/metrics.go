@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	subsystem = "delivery"
+
+	assignmentSuccess    = "success"
+	assignmentNoCouriers = "no_couriers"
+)
+
+var (
+	couriersTracked = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "couriers_tracked",
+		Help:      "Number of couriers with a known location.",
+	})
+
+	couriersStale = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "couriers_stale",
+		Help:      "Number of couriers whose location is older than the stale threshold.",
+	})
+
+	locationUpdates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "location_updates_total",
+		Help:      "Number of courier locations reported by devices.",
+	})
+
+	distanceComputations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "distance_computations_total",
+		Help:      "Number of computed source to courier distances.",
+	})
+
+	assignmentDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "assignment_duration_seconds",
+		Help:      "Latency of computing distances from a source to all couriers.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	})
+
+	assignmentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "assignments_total",
+		Help:      "Number of assignments by result.",
+	}, []string{"result"})
+
+	workersInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: subsystem,
+		Name:      "workers_in_flight",
+		Help:      "Number of distance workers currently running.",
+	})
+)
+
+// observeCouriers updates the registry gauges from the known locations
+func observeCouriers(listLoc []DeliverManLocation, staleAfter time.Duration) {
+	var stale int
+	if staleAfter > 0 {
+		for _, loc := range listLoc {
+			if !loc.UpdatedAt.IsZero() && time.Since(loc.UpdatedAt) > staleAfter {
+				stale++
+			}
+		}
+	}
+	couriersTracked.Set(float64(len(listLoc)))
+	couriersStale.Set(float64(stale))
+}
+
+func observeAssignment(start time.Time, result string) {
+	assignmentDuration.Observe(time.Since(start).Seconds())
+	assignmentsTotal.WithLabelValues(result).Inc()
+}
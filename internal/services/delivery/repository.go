@@ -0,0 +1,361 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx"
+)
+
+const (
+	// kmPerDegree is the length of a degree of a great circle of the sphere
+	// of Distance
+	kmPerDegree = 60 * 1.1515 * 1.609344
+
+	// maxDistanceKm is the distance between antipodes, no location is
+	// farther
+	maxDistanceKm = 180 * kmPerDegree
+
+	// candidateMargin enlarges the database filters, the database measures
+	// on another sphere or spheroid than Distance and must not drop
+	// locations which Distance keeps
+	candidateMargin = 1.01
+
+	// nearestStartKm is the first radius searched by Nearest without PostGIS
+	nearestStartKm = 1.0
+
+	selectLocations = "SELECT courier_id, lat, lng, updated_at FROM courier_locations"
+)
+
+var (
+	ErrInvalidRadius  = errors.New("radius must be positive")
+	ErrInvalidCount   = errors.New("count must be positive")
+	ErrInvalidPolygon = errors.New("polygon must have at least 3 points")
+)
+
+// Point is a coordinate in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// CourierDistance is a courier location and its distance to the point of a
+// spatial query
+type CourierDistance struct {
+	DeliverManLocation
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// GeoRepository stores courier locations and runs spatial queries in the
+// database. With PostGIS, i.e. the geog column of the migrations, it uses
+// ST_DWithin and KNN ordering, otherwise a bounding box prefilter. Both
+// modes keep and order the candidates by Distance, so they return the
+// same results.
+type GeoRepository struct {
+	db      dbx.DB
+	postgis bool
+}
+
+// NewGeoRepository returns a repository of the migrated courier_locations
+// table of db, PostGIS is used when the table has its geography column
+func NewGeoRepository(ctx context.Context, db dbx.DB, driverName string) (*GeoRepository, error) {
+	r := &GeoRepository{db: db}
+	switch driverName {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		var n int
+		err := db.GetContext(ctx, &n, `SELECT COUNT(*) FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = 'courier_locations' AND column_name = 'geog'`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect PostGIS: %w", err)
+		}
+		r.postgis = n > 0
+	}
+	return r, nil
+}
+
+// PostGIS reports whether spatial queries run on PostGIS
+func (r *GeoRepository) PostGIS() bool {
+	return r.postgis
+}
+
+// Upsert records the location of a courier, older locations than the
+// recorded one are ignored
+// Error ErrNoCourierID
+func (r *GeoRepository) Upsert(ctx context.Context, loc DeliverManLocation) error {
+	if loc.CourierID == "" {
+		return ErrNoCourierID
+	}
+	if loc.UpdatedAt.IsZero() {
+		loc.UpdatedAt = time.Now()
+	}
+	db := dbx.Connection(ctx, r.db)
+	_, err := db.ExecContext(ctx, db.Rebind(`INSERT INTO courier_locations (courier_id, lat, lng, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (courier_id) DO UPDATE SET lat = excluded.lat, lng = excluded.lng, updated_at = excluded.updated_at
+WHERE excluded.updated_at >= courier_locations.updated_at`),
+		loc.CourierID, loc.Lat, loc.Lng, loc.UpdatedAt.UTC())
+	return err
+}
+
+// Count returns the number of couriers whose location was updated at or
+// after since, all of them if since is zero
+func (r *GeoRepository) Count(ctx context.Context, since time.Time) (int, error) {
+	db := dbx.Connection(ctx, r.db)
+	var n int
+	err := db.GetContext(ctx, &n, db.Rebind("SELECT COUNT(*) FROM courier_locations WHERE updated_at >= ?"), since.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to count courier locations: %w", err)
+	}
+	return n, nil
+}
+
+// All returns every courier location ordered by courier ID
+func (r *GeoRepository) All(ctx context.Context) ([]DeliverManLocation, error) {
+	return r.query(ctx, selectLocations+" ORDER BY courier_id")
+}
+
+// Nearest returns the k couriers nearest to p, nearest first
+// Error ErrInvalidCount
+func (r *GeoRepository) Nearest(ctx context.Context, p Point, k int) ([]CourierDistance, error) {
+	if k <= 0 {
+		return nil, ErrInvalidCount
+	}
+	if r.postgis {
+		return r.nearestPostGIS(ctx, p, k)
+	}
+
+	for km := nearestStartKm; ; km *= 4 {
+		if km >= maxDistanceKm {
+			// every location is within the radius
+			locs, err := r.query(ctx, selectLocations)
+			if err != nil {
+				return nil, err
+			}
+			return nearest(within(locs, p, math.Inf(1)), k), nil
+		}
+		matches, err := r.withinRadius(ctx, p, km)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) >= k {
+			return nearest(matches, k), nil
+		}
+	}
+}
+
+// nearestPostGIS orders by the KNN operator, then searches the radius of
+// the k-th location by Distance since the database measures a little
+// differently
+func (r *GeoRepository) nearestPostGIS(ctx context.Context, p Point, k int) ([]CourierDistance, error) {
+	locs, err := r.query(ctx, selectLocations+" ORDER BY geog <-> ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography LIMIT ?", p.Lng, p.Lat, k)
+	if err != nil {
+		return nil, err
+	}
+	matches := within(locs, p, math.Inf(1))
+	if len(matches) < k {
+		// the table has less than k locations
+		return matches, nil
+	}
+	matches, err = r.withinRadius(ctx, p, matches[len(matches)-1].DistanceKm)
+	if err != nil {
+		return nil, err
+	}
+	return nearest(matches, k), nil
+}
+
+// WithinRadius returns the couriers at most km from p, nearest first
+// Error ErrInvalidRadius
+func (r *GeoRepository) WithinRadius(ctx context.Context, p Point, km float64) ([]CourierDistance, error) {
+	if km <= 0 || math.IsNaN(km) {
+		return nil, ErrInvalidRadius
+	}
+	return r.withinRadius(ctx, p, km)
+}
+
+func (r *GeoRepository) withinRadius(ctx context.Context, p Point, km float64) ([]CourierDistance, error) {
+	var (
+		locs []DeliverManLocation
+		err  error
+	)
+	if r.postgis {
+		locs, err = r.query(ctx, selectLocations+" WHERE ST_DWithin(geog, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?, false)",
+			p.Lng, p.Lat, km*1000*candidateMargin)
+	} else {
+		where, args := boundingBox(p, km*candidateMargin).where()
+		locs, err = r.query(ctx, selectLocations+" WHERE "+where, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return within(locs, p, km), nil
+}
+
+// WithinPolygon returns the couriers in or on the border of a polygon
+// ordered by courier ID. Edges are straight lines between coordinates,
+// as on a map, and must not cross the antimeridian.
+// Error ErrInvalidPolygon
+func (r *GeoRepository) WithinPolygon(ctx context.Context, polygon []Point) ([]DeliverManLocation, error) {
+	if len(polygon) < 3 {
+		return nil, ErrInvalidPolygon
+	}
+
+	var (
+		locs []DeliverManLocation
+		err  error
+	)
+	if r.postgis {
+		locs, err = r.query(ctx, selectLocations+" WHERE ST_Covers(ST_GeomFromText(?, 4326), ST_SetSRID(ST_MakePoint(lng, lat), 4326))", polygonWKT(polygon))
+	} else {
+		b := box{minLat: polygon[0].Lat, maxLat: polygon[0].Lat, lngs: [][2]float64{{polygon[0].Lng, polygon[0].Lng}}}
+		for _, v := range polygon[1:] {
+			b.minLat, b.maxLat = math.Min(b.minLat, v.Lat), math.Max(b.maxLat, v.Lat)
+			b.lngs[0][0], b.lngs[0][1] = math.Min(b.lngs[0][0], v.Lng), math.Max(b.lngs[0][1], v.Lng)
+		}
+		where, args := b.where()
+		locs, err = r.query(ctx, selectLocations+" WHERE "+where, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]DeliverManLocation, 0, len(locs))
+	for _, loc := range locs {
+		if covers(polygon, Point{Lat: loc.Lat, Lng: loc.Lng}) {
+			out = append(out, loc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CourierID < out[j].CourierID
+	})
+	return out, nil
+}
+
+func (r *GeoRepository) query(ctx context.Context, query string, args ...interface{}) ([]DeliverManLocation, error) {
+	db := dbx.Connection(ctx, r.db)
+	var locs []DeliverManLocation
+	if err := db.SelectContext(ctx, &locs, db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to query courier locations: %w", err)
+	}
+	return locs, nil
+}
+
+// within returns the locations at most km from p, nearest first
+func within(locs []DeliverManLocation, p Point, km float64) []CourierDistance {
+	out := make([]CourierDistance, 0, len(locs))
+	for _, loc := range locs {
+		d := Distance(p.Lat, p.Lng, loc.Lat, loc.Lng)
+		if d <= km {
+			out = append(out, CourierDistance{DeliverManLocation: loc, DistanceKm: d})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].DistanceKm != out[j].DistanceKm {
+			return out[i].DistanceKm < out[j].DistanceKm
+		}
+		return out[i].CourierID < out[j].CourierID
+	})
+	return out
+}
+
+func nearest(sorted []CourierDistance, k int) []CourierDistance {
+	if len(sorted) > k {
+		return sorted[:k]
+	}
+	return sorted
+}
+
+// box is a latitude range and one or two longitude ranges, two when it
+// crosses the antimeridian
+type box struct {
+	minLat, maxLat float64
+	lngs           [][2]float64
+}
+
+// boundingBox returns a box containing the points at most km from p
+func boundingBox(p Point, km float64) box {
+	r := km / kmPerDegree
+	b := box{minLat: p.Lat - r, maxLat: p.Lat + r}
+	if b.minLat <= -90 || b.maxLat >= 90 {
+		// a pole is within the radius, every longitude is
+		b.minLat, b.maxLat = math.Max(b.minLat, -90), math.Min(b.maxLat, 90)
+		b.lngs = [][2]float64{{-180, 180}}
+		return b
+	}
+
+	// widest longitude of a circle on a sphere, reached above its center
+	sinR, cosLat := math.Sin(r*math.Pi/180), math.Cos(p.Lat*math.Pi/180)
+	if sinR >= cosLat {
+		b.lngs = [][2]float64{{-180, 180}}
+		return b
+	}
+	dLng := math.Asin(sinR/cosLat) * 180 / math.Pi
+	minLng, maxLng := p.Lng-dLng, p.Lng+dLng
+	switch {
+	case maxLng-minLng >= 360:
+		b.lngs = [][2]float64{{-180, 180}}
+	case minLng < -180:
+		b.lngs = [][2]float64{{minLng + 360, 180}, {-180, maxLng}}
+	case maxLng > 180:
+		b.lngs = [][2]float64{{minLng, 180}, {-180, maxLng - 360}}
+	default:
+		b.lngs = [][2]float64{{minLng, maxLng}}
+	}
+	return b
+}
+
+func (b box) where() (string, []interface{}) {
+	args := []interface{}{b.minLat, b.maxLat}
+	lngs := make([]string, 0, len(b.lngs))
+	for _, r := range b.lngs {
+		lngs = append(lngs, "lng BETWEEN ? AND ?")
+		args = append(args, r[0], r[1])
+	}
+	return "lat BETWEEN ? AND ? AND (" + strings.Join(lngs, " OR ") + ")", args
+}
+
+// covers reports whether p is in polygon or on its border, the polygon is
+// planar in degrees like a geometry of SRID 4326
+func covers(polygon []Point, p Point) bool {
+	in := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if onSegment(a, b, p) {
+			return true
+		}
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lng < (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			in = !in
+		}
+	}
+	return in
+}
+
+func onSegment(a, b, p Point) bool {
+	const eps = 1e-12
+	cross := (b.Lng-a.Lng)*(p.Lat-a.Lat) - (b.Lat-a.Lat)*(p.Lng-a.Lng)
+	if math.Abs(cross) > eps {
+		return false
+	}
+	return p.Lng >= math.Min(a.Lng, b.Lng)-eps && p.Lng <= math.Max(a.Lng, b.Lng)+eps &&
+		p.Lat >= math.Min(a.Lat, b.Lat)-eps && p.Lat <= math.Max(a.Lat, b.Lat)+eps
+}
+
+// polygonWKT returns the WKT of polygon with x the longitude, the ring is
+// closed if it is not
+func polygonWKT(polygon []Point) string {
+	ring := polygon
+	if first, last := polygon[0], polygon[len(polygon)-1]; first != last {
+		ring = append(append([]Point(nil), polygon...), first)
+	}
+	coords := make([]string, 0, len(ring))
+	for _, v := range ring {
+		coords = append(coords, strconv.FormatFloat(v.Lng, 'f', -1, 64)+" "+strconv.FormatFloat(v.Lat, 'f', -1, 64))
+	}
+	return "POLYGON((" + strings.Join(coords, ", ") + "))"
+}
@@ -0,0 +1,285 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx/migrate"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) *GeoRepository {
+	t.Helper()
+	ctx := context.Background()
+	db, err := dbx.Open(ctx, "sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migs, err := migrate.Load(migrations.FS, migrate.SQLite)
+	require.NoError(t, err)
+	m, err := migrate.New(db, migs)
+	require.NoError(t, err)
+	_, err = m.Up(ctx, 0)
+	require.NoError(t, err)
+
+	r, err := NewGeoRepository(ctx, db, "sqlite3")
+	require.NoError(t, err)
+	assert.False(t, r.PostGIS())
+	return r
+}
+
+// randomLocations returns couriers around Tehran, around the antimeridian
+// and near the north pole
+func randomLocations(n int) []DeliverManLocation {
+	rnd := rand.New(rand.NewSource(1))
+	centers := []Point{{Lat: 35.7, Lng: 51.4}, {Lat: -17, Lng: 179.9}, {Lat: 89.5, Lng: 0}}
+	locs := make([]DeliverManLocation, 0, n)
+	for i := 0; i < n; i++ {
+		c := centers[i%len(centers)]
+		lng := c.Lng + rnd.Float64()*2 - 1
+		if lng > 180 {
+			lng -= 360
+		}
+		locs = append(locs, DeliverManLocation{
+			CourierID: fmt.Sprintf("c%03d", i),
+			Lat:       c.Lat + rnd.Float64()*0.9 - 0.45,
+			Lng:       lng,
+			UpdatedAt: time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC),
+		})
+	}
+	return locs
+}
+
+// bruteForce returns the locations at most km from p, nearest first
+func bruteForce(locs []DeliverManLocation, p Point, km float64) []CourierDistance {
+	var out []CourierDistance
+	for _, loc := range locs {
+		if d := Distance(p.Lat, p.Lng, loc.Lat, loc.Lng); d <= km {
+			out = append(out, CourierDistance{DeliverManLocation: loc, DistanceKm: d})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].DistanceKm < out[j].DistanceKm
+	})
+	return out
+}
+
+func courierIDs(matches []CourierDistance) []string {
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.CourierID)
+	}
+	return out
+}
+
+// geoFixtures runs radius, nearest and polygon queries around the points
+// of randomLocations, checks them against a brute force search of locs and
+// returns the courier IDs found by each query
+func geoFixtures(t *testing.T, r *GeoRepository, locs []DeliverManLocation) map[string][]string {
+	t.Helper()
+	ctx := context.Background()
+	out := map[string][]string{}
+
+	points := []Point{
+		{Lat: 35.7, Lng: 51.4},
+		{Lat: -17, Lng: -179.95},
+		{Lat: -17, Lng: 179.95},
+		{Lat: 89.9, Lng: 120},
+		{Lat: -60, Lng: 0},
+	}
+	for _, p := range points {
+		for _, km := range []float64{5, 30, 80, 500} {
+			name := fmt.Sprintf("radius %v %v", p, km)
+			t.Run(name, func(t *testing.T) {
+				got, err := r.WithinRadius(ctx, p, km)
+				require.NoError(t, err)
+				assert.Equal(t, courierIDs(bruteForce(locs, p, km)), courierIDs(got))
+				out[name] = courierIDs(got)
+			})
+		}
+		for _, k := range []int{1, 7, 120, 500} {
+			name := fmt.Sprintf("nearest %v %d", p, k)
+			t.Run(name, func(t *testing.T) {
+				got, err := r.Nearest(ctx, p, k)
+				require.NoError(t, err)
+				want := bruteForce(locs, p, maxDistanceKm+1)
+				if len(want) > k {
+					want = want[:k]
+				}
+				assert.Equal(t, courierIDs(want), courierIDs(got))
+				if len(got) > 0 {
+					assert.InDelta(t, want[0].DistanceKm, got[0].DistanceKm, 1e-9)
+				}
+				out[name] = courierIDs(got)
+			})
+		}
+	}
+
+	t.Run("polygon", func(t *testing.T) {
+		polygon := []Point{{Lat: 35.5, Lng: 51}, {Lat: 35.5, Lng: 51.8}, {Lat: 36, Lng: 51.4}}
+		got, err := r.WithinPolygon(ctx, polygon)
+		require.NoError(t, err)
+		var want []string
+		for _, loc := range locs {
+			if covers(polygon, Point{Lat: loc.Lat, Lng: loc.Lng}) {
+				want = append(want, loc.CourierID)
+			}
+		}
+		require.NotEmpty(t, want)
+		var ids []string
+		for _, loc := range got {
+			ids = append(ids, loc.CourierID)
+		}
+		assert.Equal(t, want, ids)
+		out["polygon"] = ids
+	})
+	return out
+}
+
+func TestGeoRepository(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	locs := randomLocations(300)
+	for _, loc := range locs {
+		require.NoError(t, r.Upsert(ctx, loc))
+	}
+	geoFixtures(t, r, locs)
+}
+
+// TestGeoRepositoryPostgres runs the fixtures on the Postgres database of
+// DCD_TEST_POSTGRES_DSN, with PostGIS when the server ships it and with
+// the bounding box prefilter, and compares the results of both modes. The
+// database must be disposable, the migrations are reverted afterwards.
+func TestGeoRepositoryPostgres(t *testing.T) {
+	dsn := os.Getenv("DCD_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("DCD_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	db, err := dbx.Open(ctx, "postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migs, err := migrate.Load(migrations.FS, migrate.Postgres)
+	require.NoError(t, err)
+	m, err := migrate.New(db, migs, migrate.WithTable("dcd_test_migrations"))
+	require.NoError(t, err)
+	applied, err := m.Up(ctx, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := m.Down(ctx, len(applied))
+		assert.NoError(t, err)
+		_, err = db.Exec("DROP TABLE dcd_test_migrations")
+		assert.NoError(t, err)
+	})
+
+	postgis, err := NewGeoRepository(ctx, db, "postgres")
+	require.NoError(t, err)
+	locs := randomLocations(300)
+	for _, loc := range locs {
+		require.NoError(t, postgis.Upsert(ctx, loc))
+	}
+
+	var bboxResults map[string][]string
+	t.Run("bounding box", func(t *testing.T) {
+		bboxResults = geoFixtures(t, &GeoRepository{db: db}, locs)
+	})
+	if !postgis.PostGIS() {
+		t.Log("PostGIS is not available, only the bounding box mode ran")
+		return
+	}
+	t.Run("postgis", func(t *testing.T) {
+		assert.Equal(t, bboxResults, geoFixtures(t, postgis, locs))
+	})
+}
+
+func TestGeoRepositoryUpsert(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	at := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, r.Upsert(ctx, DeliverManLocation{CourierID: "c1", Lat: 1, Lng: 1, UpdatedAt: at}))
+	require.NoError(t, r.Upsert(ctx, DeliverManLocation{CourierID: "c1", Lat: 2, Lng: 2, UpdatedAt: at.Add(-time.Minute)}))
+	got, err := r.Nearest(ctx, Point{}, 5)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, 1.0, got[0].Lat, "older locations are ignored")
+	assert.True(t, at.Equal(got[0].UpdatedAt))
+
+	require.NoError(t, r.Upsert(ctx, DeliverManLocation{CourierID: "c1", Lat: 3, Lng: 3, UpdatedAt: at.Add(time.Minute)}))
+	got, err = r.Nearest(ctx, Point{}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, got[0].Lat)
+
+	require.NoError(t, r.Upsert(ctx, DeliverManLocation{CourierID: "c0", Lat: -89, Lng: 179, UpdatedAt: at}))
+	all, err := r.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2, "antipodes are included")
+	assert.Equal(t, "c0", all[0].CourierID, "ordered by courier ID")
+	assert.Equal(t, "c1", all[1].CourierID)
+
+	assert.ErrorIs(t, r.Upsert(ctx, DeliverManLocation{Lat: 1}), ErrNoCourierID)
+	_, err = r.Nearest(ctx, Point{}, 0)
+	assert.ErrorIs(t, err, ErrInvalidCount)
+	_, err = r.WithinRadius(ctx, Point{}, 0)
+	assert.ErrorIs(t, err, ErrInvalidRadius)
+	_, err = r.WithinPolygon(ctx, []Point{{}, {Lat: 1}})
+	assert.ErrorIs(t, err, ErrInvalidPolygon)
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Point
+		km   float64
+		lngs int
+		full bool
+	}{
+		{name: "mid latitude", p: Point{Lat: 35.7, Lng: 51.4}, km: 50, lngs: 1},
+		{name: "east of antimeridian", p: Point{Lat: -17, Lng: -179.9}, km: 50, lngs: 2},
+		{name: "west of antimeridian", p: Point{Lat: -17, Lng: 179.9}, km: 50, lngs: 2},
+		{name: "pole within radius", p: Point{Lat: 89.9, Lng: 10}, km: 50, lngs: 1, full: true},
+		{name: "whole earth", p: Point{Lat: 0, Lng: 0}, km: maxDistanceKm, lngs: 1, full: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := boundingBox(tt.p, tt.km)
+			require.Len(t, b.lngs, tt.lngs)
+			if tt.full {
+				assert.Equal(t, [2]float64{-180, 180}, b.lngs[0])
+			}
+			assert.LessOrEqual(t, b.minLat, tt.p.Lat)
+			assert.GreaterOrEqual(t, b.maxLat, tt.p.Lat)
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	square := []Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 10}, {Lat: 10, Lng: 10}, {Lat: 10, Lng: 0}}
+	tests := []struct {
+		name string
+		p    Point
+		want bool
+	}{
+		{name: "inside", p: Point{Lat: 5, Lng: 5}, want: true},
+		{name: "outside", p: Point{Lat: 5, Lng: 11}},
+		{name: "on edge", p: Point{Lat: 0, Lng: 5}, want: true},
+		{name: "on vertex", p: Point{Lat: 10, Lng: 10}, want: true},
+		{name: "beyond edge", p: Point{Lat: 0, Lng: 11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, covers(square, tt.p))
+		})
+	}
+	assert.Equal(t, "POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))", polygonWKT(square))
+}
@@ -1,7 +1,21 @@
 package delivery
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
+	"sort"
+	"sync"
+	"time"
+
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+)
+
+var (
+	ErrNoCouriers    = errors.New("no courier locations are known")
+	ErrStaleCouriers = errors.New("all courier locations are stale")
+	ErrNoCourierID   = errors.New("courier id is required")
 )
 
 type (
@@ -10,28 +24,203 @@ type (
 		Lng float64
 	}
 	DeliverManLocation struct {
-		Lat float64 `json:"lat"`
-		Lng float64 `json:"lng"`
+		// CourierID is empty for locations of the static configuration
+		CourierID string  `json:"courier_id,omitempty" db:"courier_id"`
+		Lat       float64 `json:"lat" db:"lat"`
+		Lng       float64 `json:"lng" db:"lng"`
+		// UpdatedAt is the time the location was reported, zero if unknown
+		UpdatedAt time.Time `json:"updated_at,omitempty" db:"updated_at"`
 	}
 )
 
-func (s *UseCase) GetDistance(souLoc SourceLocation, deliLoc []DeliverManLocation) interface{} {
-	c := make(chan float64)
-	for i := 0; i < len(deliLoc); i++ {
-		go s.CalculateDist(souLoc.Lat, souLoc.Lng, deliLoc[i].Lat, deliLoc[i].Lng, c)
+// Couriers returns the known courier locations: the configured ones
+// followed by the reported ones ordered by courier ID
+func (s *UseCase) Couriers(ctx context.Context) ([]DeliverManLocation, error) {
+	listLoc := make([]DeliverManLocation, 0, len(s.cfg.Delivery.Couriers))
+	for _, loc := range s.cfg.Delivery.Couriers {
+		listLoc = append(listLoc, DeliverManLocation{Lat: loc.Lat, Lng: loc.Lng})
+	}
+
+	reported, err := s.reportedLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(reported, func(i, j int) bool {
+		return reported[i].CourierID < reported[j].CourierID
+	})
+	listLoc = append(listLoc, reported...)
+
+	observeCouriers(listLoc, s.cfg.Delivery.CourierStaleAfter)
+	return listLoc, nil
+}
+
+func (s *UseCase) reportedLocations(ctx context.Context) ([]DeliverManLocation, error) {
+	if s.geo != nil {
+		reported, err := s.geo.All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load reported locations: %w", err)
+		}
+		return reported, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reported := make([]DeliverManLocation, 0, len(s.reported))
+	for _, loc := range s.reported {
+		reported = append(reported, loc)
+	}
+	return reported, nil
+}
+
+// UpdateLocation records the location reported by a courier, older reports
+// than the recorded one are ignored
+func (s *UseCase) UpdateLocation(ctx context.Context, loc DeliverManLocation) error {
+	if loc.CourierID == "" {
+		return ErrNoCourierID
+	}
+	if loc.UpdatedAt.IsZero() {
+		loc.UpdatedAt = time.Now()
+	}
+
+	if s.geo != nil {
+		if err := s.geo.Upsert(ctx, loc); err != nil {
+			return err
+		}
+		locationUpdates.Inc()
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.reported[loc.CourierID]; ok && prev.UpdatedAt.After(loc.UpdatedAt) {
+		return nil
+	}
+	s.reported[loc.CourierID] = loc
+	locationUpdates.Inc()
+	return nil
+}
+
+// CheckCouriers reports whether the courier registry is loaded and fresh.
+// Locations without a report time, i.e. the configured ones, are
+// considered fresh.
+func (s *UseCase) CheckCouriers(ctx context.Context) error {
+	if len(s.cfg.Delivery.Couriers) > 0 {
+		return nil
+	}
+	var since time.Time
+	if s.cfg.Delivery.CourierStaleAfter > 0 {
+		since = time.Now().Add(-s.cfg.Delivery.CourierStaleAfter)
+	}
+	fresh, err := s.countReported(ctx, since)
+	if err != nil || fresh > 0 {
+		return err
 	}
+	if !since.IsZero() {
+		total, err := s.countReported(ctx, time.Time{})
+		if err != nil {
+			return err
+		}
+		if total > 0 {
+			return ErrStaleCouriers
+		}
+	}
+	return ErrNoCouriers
+}
+
+// countReported returns the number of reported locations updated at or
+// after since, all of them if since is zero
+func (s *UseCase) countReported(ctx context.Context, since time.Time) (int, error) {
+	if s.geo != nil {
+		return s.geo.Count(ctx, since)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var n int
+	for _, loc := range s.reported {
+		if !loc.UpdatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GetDistance returns the distances in kilometers from souLoc to deliLoc,
+// in the order of deliLoc. With a repository the distances to reported
+// locations are measured by its nearest neighbour query.
+func (s *UseCase) GetDistance(ctx context.Context, souLoc SourceLocation, deliLoc []DeliverManLocation) interface{} {
+	start := time.Now()
+	if len(deliLoc) == 0 {
+		observeAssignment(start, assignmentNoCouriers)
+	} else {
+		defer observeAssignment(start, assignmentSuccess)
+	}
+
+	known := s.reportedDistances(ctx, souLoc, deliLoc)
 	var output []float64
-	for l := 0; l < len(deliLoc); l++ {
-		output = append(output, <-c)
+	if len(deliLoc) > 0 {
+		output = make([]float64, len(deliLoc))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < len(deliLoc); i++ {
+		if dist, ok := known[deliLoc[i].CourierID]; ok {
+			output[i] = dist
+			continue
+		}
+		wg.Add(1)
+		workersInFlight.Inc()
+		go func(i int, loc DeliverManLocation) {
+			defer wg.Done()
+			defer workersInFlight.Dec()
+			c := make(chan float64, 1)
+			s.CalculateDist(souLoc.Lat, souLoc.Lng, loc.Lat, loc.Lng, c)
+			output[i] = <-c
+		}(i, deliLoc[i])
 	}
-	close(c)
+	wg.Wait()
 	return output
 }
 
+// reportedDistances returns the distances from souLoc to the reported
+// locations of deliLoc by courier ID, nil without a repository. Locations
+// missing from the result are measured by GetDistance itself.
+func (s *UseCase) reportedDistances(ctx context.Context, souLoc SourceLocation, deliLoc []DeliverManLocation) map[string]float64 {
+	if s.geo == nil {
+		return nil
+	}
+	var k int
+	for _, loc := range deliLoc {
+		if loc.CourierID != "" {
+			k++
+		}
+	}
+	if k == 0 {
+		return nil
+	}
+	matches, err := s.geo.Nearest(ctx, Point{Lat: souLoc.Lat, Lng: souLoc.Lng}, k)
+	if err != nil {
+		s.logger.Warn("failed to query courier distances, measuring them in memory", loggerx.Error(err))
+		return nil
+	}
+	out := make(map[string]float64, len(matches))
+	for _, m := range matches {
+		out[m.CourierID] = m.DistanceKm
+	}
+	return out
+}
+
 func (s *UseCase) CalculateDist(sourceX float64, sourceY float64, DeliverManX float64, DeliverManY float64, c chan float64) {
-	radSourceX := math.Pi * sourceX / 180
-	radDeliverManX := math.Pi * DeliverManX / 180
-	theta := sourceY - DeliverManY
+	dist := Distance(sourceX, sourceY, DeliverManX, DeliverManY)
+	distanceComputations.Inc()
+	c <- dist
+}
+
+// Distance returns the great-circle distance in kilometers between two
+// coordinates in degrees
+func Distance(sourceLat, sourceLng, lat, lng float64) float64 {
+	radSourceX := math.Pi * sourceLat / 180
+	radDeliverManX := math.Pi * lat / 180
+	theta := sourceLng - lng
 	radTheTa := math.Pi * theta / 180
 
 	dist := math.Sin(radSourceX)*math.Sin(radDeliverManX) + math.Cos(radSourceX)*math.Cos(radDeliverManX)*math.Cos(radTheTa)
@@ -42,6 +231,5 @@ func (s *UseCase) CalculateDist(sourceX float64, sourceY float64, DeliverManX fl
 	dist = dist * 180 / math.Pi
 	dist = dist * 60 * 1.1515
 	dist = dist * 1.609344
-
-	c <- dist
+	return dist
 }
@@ -0,0 +1,82 @@
+package delivery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
+	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseCaseGeoRepository(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Delivery.Source = config.Location{Lat: 35.7, Lng: 51.4}
+	cfg.Delivery.Couriers = []config.Location{{Lat: 35.71, Lng: 51.41}}
+
+	memory := NewDeliveryUseCase(cfg, loggerx.NewTestLogger())
+	geo := NewDeliveryUseCase(cfg, loggerx.NewTestLogger(), WithGeoRepository(newTestRepository(t)))
+	for _, loc := range randomLocations(60) {
+		require.NoError(t, memory.UpdateLocation(ctx, loc))
+		require.NoError(t, geo.UpdateLocation(ctx, loc))
+	}
+	stale := randomLocations(1)[0]
+	stale.UpdatedAt = stale.UpdatedAt.Add(-time.Hour)
+	stale.Lat = 0
+	require.NoError(t, geo.UpdateLocation(ctx, stale))
+	assert.ErrorIs(t, geo.UpdateLocation(ctx, DeliverManLocation{Lat: 1}), ErrNoCourierID)
+
+	want, err := memory.Couriers(ctx)
+	require.NoError(t, err)
+	got, err := geo.Couriers(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 61)
+	assert.Equal(t, want[0], got[0], "configured couriers come first")
+	for i := range want {
+		assert.Equal(t, want[i].CourierID, got[i].CourierID)
+		assert.Equal(t, want[i].Lat, got[i].Lat)
+		assert.True(t, want[i].UpdatedAt.Equal(got[i].UpdatedAt))
+	}
+
+	source := SourceLocation{Lat: cfg.Delivery.Source.Lat, Lng: cfg.Delivery.Source.Lng}
+	wantDist := memory.GetDistance(ctx, source, want).([]float64)
+	gotDist := geo.GetDistance(ctx, source, got).([]float64)
+	require.Len(t, gotDist, len(got))
+	for i, loc := range got {
+		assert.InDelta(t, Distance(source.Lat, source.Lng, loc.Lat, loc.Lng), gotDist[i], 1e-9, "in the order of the locations")
+	}
+	assert.InDeltaSlice(t, wantDist, gotDist, 1e-9)
+	assert.Nil(t, memory.GetDistance(ctx, source, nil))
+}
+
+func TestCheckCouriers(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Delivery.Couriers = nil
+	cfg.Delivery.CourierStaleAfter = time.Minute
+
+	for name, opts := range map[string][]Option{
+		"memory": nil,
+		"geo":    {WithGeoRepository(newTestRepository(t))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			uc := NewDeliveryUseCase(cfg, loggerx.NewTestLogger(), opts...)
+			assert.ErrorIs(t, uc.CheckCouriers(ctx), ErrNoCouriers)
+
+			loc := DeliverManLocation{CourierID: "c1", Lat: 35.7, Lng: 51.4, UpdatedAt: time.Now().Add(-time.Hour)}
+			require.NoError(t, uc.UpdateLocation(ctx, loc))
+			assert.ErrorIs(t, uc.CheckCouriers(ctx), ErrStaleCouriers)
+
+			loc.UpdatedAt = time.Now()
+			require.NoError(t, uc.UpdateLocation(ctx, loc))
+			assert.NoError(t, uc.CheckCouriers(ctx))
+		})
+	}
+
+	configured := *cfg
+	configured.Delivery.Couriers = []config.Location{{Lat: 35.71, Lng: 51.41}}
+	assert.NoError(t, NewDeliveryUseCase(&configured, loggerx.NewTestLogger()).CheckCouriers(ctx), "configured couriers are fresh")
+}
@@ -1,6 +1,9 @@
 package delivery
 
 import (
+	"context"
+	"sync"
+
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
 	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
 )
@@ -8,16 +11,41 @@ import (
 type UseCase struct {
 	cfg    *config.Config
 	logger *loggerx.Logger
+	// geo stores the reported locations when the database is configured
+	geo *GeoRepository
+
+	mu sync.RWMutex
+	// reported holds the last location reported by each courier when
+	// there is no repository
+	reported map[string]DeliverManLocation
 }
 
-func NewDeliveryUseCase(cfg *config.Config, logger *loggerx.Logger) *UseCase {
-	return &UseCase{
-		cfg:    cfg,
-		logger: logger,
+type Option func(*UseCase)
+
+// WithGeoRepository keeps the reported locations in the database and
+// measures the distances to them with its spatial queries
+func WithGeoRepository(r *GeoRepository) Option {
+	return func(s *UseCase) {
+		s.geo = r
+	}
+}
+
+func NewDeliveryUseCase(cfg *config.Config, logger *loggerx.Logger, opts ...Option) *UseCase {
+	s := &UseCase{
+		cfg:      cfg,
+		logger:   logger,
+		reported: make(map[string]DeliverManLocation),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 type UseService interface {
-	GetDistance(sorLoc SourceLocation, deliLocs []DeliverManLocation) interface{}
+	Couriers(ctx context.Context) ([]DeliverManLocation, error)
+	UpdateLocation(ctx context.Context, loc DeliverManLocation) error
+	CheckCouriers(ctx context.Context) error
+	GetDistance(ctx context.Context, sorLoc SourceLocation, deliLocs []DeliverManLocation) interface{}
 	CalculateDist(sourceX float64, sourceY float64, DeliverManX float64, DeliverManY float64, c chan float64)
 }
@@ -0,0 +1,8 @@
+CREATE TABLE courier_locations (
+    courier_id TEXT PRIMARY KEY,
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX courier_locations_updated_at_idx ON courier_locations (updated_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS courier_locations_geog_idx;
+ALTER TABLE courier_locations DROP COLUMN IF EXISTS geog;
+DROP INDEX courier_locations_lat_lng_idx;
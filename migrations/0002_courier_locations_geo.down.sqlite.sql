@@ -0,0 +1 @@
+DROP INDEX courier_locations_lat_lng_idx;
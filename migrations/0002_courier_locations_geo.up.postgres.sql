@@ -0,0 +1,15 @@
+-- bounding box prefilter of spatial queries when PostGIS is not available
+CREATE INDEX courier_locations_lat_lng_idx ON courier_locations (lat, lng);
+
+-- a geography column with a GiST index for ST_DWithin and KNN queries,
+-- added only when the server ships PostGIS
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'postgis') THEN
+        CREATE EXTENSION IF NOT EXISTS postgis;
+        ALTER TABLE courier_locations ADD COLUMN geog geography(Point, 4326)
+            GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(lng, lat), 4326)::geography) STORED;
+        CREATE INDEX courier_locations_geog_idx ON courier_locations USING GIST (geog);
+    END IF;
+END
+$$;
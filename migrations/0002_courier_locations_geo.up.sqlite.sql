@@ -0,0 +1,2 @@
+-- bounding box prefilter of spatial queries
+CREATE INDEX courier_locations_lat_lng_idx ON courier_locations (lat, lng);
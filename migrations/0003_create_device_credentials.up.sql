@@ -0,0 +1,12 @@
+CREATE TABLE device_credentials (
+    token_hash TEXT PRIMARY KEY,
+    courier_id TEXT NOT NULL,
+    device_id TEXT NOT NULL,
+    issued_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    last_ip TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX device_credentials_courier_id_idx ON device_credentials (courier_id, issued_at);
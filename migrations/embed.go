@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL migrations of the service database.
+//
+// Files are named VERSION_NAME.(up|down)[.DIALECT].sql, e.g.
+// 0001_create_courier_locations.up.sql. A file with a postgres or sqlite
+// dialect replaces the generic file of its version on that dialect.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -2,40 +2,233 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/config"
 	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/api/v1"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/auth"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/dbx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/health"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/httpx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/lifecycle"
 	loggerx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/log"
-	httpx "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/http"
-	"log"
-	"os"
-	"os/signal"
-	"time"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tlsx"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/common/tracing"
+	httpserver "github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/http"
+	"github.com/aliakbariaa1996/Calculate-Deliver-To-Destination/internal/services/delivery"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"strings"
+	"syscall"
 )
 
 func RunServer(cfg *config.Config, logger *loggerx.Logger) error {
+	lc := lifecycle.New(logger.Named("lifecycle"),
+		lifecycle.WithShutdownTimeout(cfg.Server.ShutdownTimeout),
+		lifecycle.WithDrainDelay(cfg.Server.ShutdownDrainDelay),
+	)
+
+	tracer, closer, err := tracing.Init(tracing.Config{
+		ServiceName:  cfg.Tracing.ServiceName,
+		Exporter:     cfg.Tracing.Exporter,
+		Endpoint:     cfg.Tracing.Endpoint,
+		SamplerType:  cfg.Tracing.SamplerType,
+		SamplerParam: cfg.Tracing.SamplerParam,
+	})
+	if err != nil {
+		return err
+	}
+	// registered first so spans of the drained requests are flushed last
+	lc.OnShutdown("tracing", func(_ context.Context) error {
+		return closer.Close()
+	})
+
+	checker := health.New(health.WithReadiness(lc.Ready))
+	checker.Register("config", func(_ context.Context) error {
+		return cfg.Validate()
+	})
+	checker.RegisterOptional("tracing", tracing.Check)
+
+	// courier locations and device credentials are kept in memory without
+	// a database
+	var (
+		geo     *delivery.GeoRepository
+		devices auth.DeviceStore
+	)
+	if cfg.DB.DSN != "" {
+		cluster, err := newDatabase(cfg, logger, lc, checker)
+		if err != nil {
+			return err
+		}
+		geo, err = delivery.NewGeoRepository(context.Background(), cluster, cfg.DB.Driver)
+		if err != nil {
+			return err
+		}
+		devices = auth.NewSQLDeviceStore(cluster)
+	}
+
+	lc.OnSignal(syscall.SIGUSR1, logger.ToggleDebug)
+
+	proxies, err := httpx.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	httpx.SetTrustedProxies(proxies)
+
+	authenticators, err := newAuthenticators(cfg, lc)
+	if err != nil {
+		return err
+	}
+	if len(authenticators) == 0 {
+		logger.Warn("no authentication is configured, the v1 API is open")
+	}
+
 	// HTTP Server
-	router := httpx.InitRouter()
-	server, err := v1.NewServer(router, cfg, logger)
+	router := httpserver.InitRouter(cfg, logger, tracer)
+	server, err := v1.NewServer(router, cfg, logger, checker, authenticators, geo, devices)
 	if err != nil {
 		return err
 	}
-	server.Server.Addr = ":" + cfg.Port
+	server.Server.Addr = ":" + cfg.Server.Port
 	server.Server.Handler = router
-	server.Server.ReadTimeout = 10 * time.Second
-	server.Server.WriteTimeout = 10 * time.Second
+	server.Server.ReadTimeout = cfg.Server.ReadTimeout
+	server.Server.WriteTimeout = cfg.Server.WriteTimeout
 	server.Server.MaxHeaderBytes = 1 << 20
-	go func() {
-		if err := server.Server.ListenAndServe(); err != nil {
-			log.Fatalf("Failed to listen and serve: %+v", err)
+
+	serve := server.Server.ListenAndServe
+	if cfg.Server.TLS.Enabled() {
+		certs, err := newCertReloader(cfg, logger, lc, checker)
+		if err != nil {
+			return err
+		}
+		server.Server.TLSConfig = certs.TLSConfig()
+		serve = func() error {
+			// the certificate is provided by the TLS config
+			return server.Server.ListenAndServeTLS("", "")
+		}
+	}
+
+	lc.Go("http", func(_ context.Context) error {
+		logger.Infof("listening on %s", server.Server.Addr)
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to listen and serve: %w", err)
+		}
+		return nil
+	})
+	lc.OnShutdown("http", server.Server.Shutdown)
+
+	return lc.Run()
+}
+
+// newAuthenticators returns the authenticators of the v1 API, API keys are
+// tried before bearer tokens
+func newAuthenticators(cfg *config.Config, lc *lifecycle.Manager) ([]auth.Authenticator, error) {
+	var out []auth.Authenticator
+	if len(cfg.Auth.APIKeys) > 0 {
+		clients := make([]auth.Client, 0, len(cfg.Auth.APIKeys))
+		for name, key := range cfg.Auth.APIKeys {
+			clients = append(clients, auth.Client{
+				Name:   name,
+				Key:    key,
+				Scopes: strings.Fields(cfg.Auth.APIKeyScopes[name]),
+			})
+		}
+		out = append(out, auth.NewAPIKeys(clients...))
+	}
+
+	if cfg.Auth.JWT.Secret != "" || cfg.Auth.JWT.JWKSFile != "" {
+		jwt, err := auth.NewJWT(auth.JWTConfig{
+			Secret:   cfg.Auth.JWT.Secret,
+			JWKSFile: cfg.Auth.JWT.JWKSFile,
+			Issuer:   cfg.Auth.JWT.Issuer,
+			Audience: cfg.Auth.JWT.Audience,
+		})
+		if err != nil {
+			return nil, err
+		}
+		lc.OnReload("jwks", jwt.Reload)
+		out = append(out, jwt)
+	}
+
+	if cfg.Server.TLS.Enabled() && cfg.Server.TLS.ClientCAFile != "" {
+		scopes := make(map[string][]string, len(cfg.Auth.MTLSScopes))
+		for cn, s := range cfg.Auth.MTLSScopes {
+			scopes[cn] = strings.Fields(s)
+		}
+		out = append(out, auth.NewClientCerts(scopes))
+	}
+	return out, nil
+}
+
+// newDatabase opens the primary database and its read replicas, queries
+// are instrumented. Replicas are checked once before the server starts and
+// then in the background.
+func newDatabase(cfg *config.Config, logger *loggerx.Logger, lc *lifecycle.Manager, checker *health.Checker) (*dbx.Cluster, error) {
+	dbLogger := logger.Named("db")
+	open := func(name, dsn string) (*sqlx.DB, error) {
+		db, err := dbx.Open(context.Background(), cfg.DB.Driver, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
 		}
-	}()
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, os.Interrupt)
+		lc.OnShutdown(name, func(_ context.Context) error {
+			return db.Close()
+		})
+		return db, nil
+	}
+	instrument := func(db *sqlx.DB) dbx.DB {
+		return dbx.Instrument(db, cfg.DB.Driver, dbLogger, dbx.WithSlowQuery(cfg.DB.SlowQuery))
+	}
 
-	<-quit
+	primary, err := open("database", cfg.DB.DSN)
+	if err != nil {
+		return nil, err
+	}
+	checker.Register("database", dbx.Ping(primary))
 
-	ctx, shutdown := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdown()
+	replicas := make([]dbx.DB, 0, len(cfg.DB.Replicas))
+	for i, dsn := range cfg.DB.Replicas {
+		replica, err := open(fmt.Sprintf("database_replica_%d", i), dsn)
+		if err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, instrument(replica))
+	}
+	cluster := dbx.NewCluster(instrument(primary), cfg.DB.Driver, replicas, dbx.WithMaxLag(cfg.DB.ReplicaMaxLag))
+	if len(replicas) > 0 {
+		// reads fall back to the primary, unhealthy replicas do not make
+		// the service unready
+		checker.RegisterOptional("database_replicas", cluster.Check)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DB.ReplicaCheckInterval)
+		for _, status := range cluster.CheckReplicas(ctx) {
+			if !status.Healthy {
+				dbLogger.Warn("replica is not read until it recovers",
+					loggerx.String("replica", status.Name), loggerx.Error(status.Err))
+			}
+		}
+		cancel()
+		lc.Go("database-replicas", func(ctx context.Context) error {
+			return cluster.Watch(ctx, cfg.DB.ReplicaCheckInterval)
+		})
+	}
+	return cluster, nil
+}
 
-	return server.Server.Shutdown(ctx)
-}
\ No newline at end of file
+// newCertReloader loads the server certificate, it is reloaded on SIGHUP
+// and when the files are modified
+func newCertReloader(cfg *config.Config, logger *loggerx.Logger, lc *lifecycle.Manager, checker *health.Checker) (*tlsx.Reloader, error) {
+	certs, err := tlsx.NewReloader(cfg.Server.TLS.Config())
+	if err != nil {
+		return nil, err
+	}
+	lc.OnReload("tls", certs.Reload)
+	if cfg.Server.TLS.ReloadInterval > 0 {
+		tlsLogger := logger.Named("tls")
+		lc.Go("tls-watch", func(ctx context.Context) error {
+			return certs.Watch(ctx, cfg.Server.TLS.ReloadInterval, func(err error) {
+				tlsLogger.Error("failed to reload certificate", loggerx.Error(err))
+			})
+		})
+	}
+	checker.RegisterOptional("tls", certs.Check)
+	return certs, nil
+}